@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"geo_system_core/internal/auth"
+	"geo_system_core/internal/config"
+	"geo_system_core/internal/migrate"
+	"geo_system_core/internal/models"
+	"geo_system_core/internal/repository/postgres"
+	"geo_system_core/internal/repository/redis"
+	"geo_system_core/internal/router"
+	"geo_system_core/internal/service"
+	"geo_system_core/migrations"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run запускает сервер или команду и возвращает ошибку запуска, команды или HTTP-сервера, завершившегося
+// не по сигналу. Выход с ненулевым кодом выполняется только в main, после закрытия соединений отложенными вызовами run.
+func run() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	ctx := context.Background()
+
+	// Подключение к PostgreSQL
+	pool, err := pgxpool.New(ctx, cfg.Database.DSN())
+	if err != nil {
+		return fmt.Errorf("failed to create database pool: %w", err)
+	}
+	defer pool.Close()
+	if err := pool.Ping(ctx); err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	migrator, err := migrate.New(pool, migrations.FS)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	// server migrate up|down [N]|status — управление схемой без запуска сервера
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(ctx, migrator, os.Args[2:]); err != nil {
+			return fmt.Errorf("migrate: %w", err)
+		}
+		return nil
+	}
+
+	if cfg.Database.MigrateOnStart {
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to apply migrations: %w", err)
+		}
+		if len(applied) > 0 {
+			log.Printf("applied migrations: %v", applied)
+		}
+	}
+
+	// server apikey create <имя> <области через запятую> [арендатор] — выпуск ключа без API_KEY
+	if len(os.Args) > 1 && os.Args[1] == "apikey" {
+		apiKeyService := service.NewAPIKeyService(postgres.NewAPIKeyRepository(pool), nil, "", nil)
+		if err := runAPIKey(ctx, apiKeyService, os.Args[2:]); err != nil {
+			return fmt.Errorf("apikey: %w", err)
+		}
+		return nil
+	}
+
+	// Подключение к Redis
+	redisClient := goredis.NewClient(&goredis.Options{
+		Addr:     cfg.Redis.Addr(),
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+	defer redisClient.Close()
+	if err := redisClient.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	// Инициализация репозиториев
+	incidentRepo := postgres.NewIncidentRepository(pool)
+	locationRepo := postgres.NewLocationRepository(pool)
+	subscriptionRepo := postgres.NewSubscriptionRepository(pool)
+	apiKeyRepo := postgres.NewAPIKeyRepository(pool)
+	tenantRepo := postgres.NewTenantRepository(pool)
+	queueRepo := redis.NewQueueRepository(redisClient)
+	geofenceRepo := redis.NewGeofenceRepository(redisClient)
+	deadLetterRepo := redis.NewDeadLetterRepository(redisClient)
+	eventRepo := redis.NewEventRepository(redisClient, int64(cfg.Events.StreamLength))
+
+	background := service.NewBackground()
+	r := router.SetupRouter(cfg, background, pool, incidentRepo, locationRepo, queueRepo, geofenceRepo, deadLetterRepo, eventRepo, subscriptionRepo, apiKeyRepo, tenantRepo)
+
+	srv := &http.Server{
+		Addr:    net.JoinHostPort(cfg.Server.Host, cfg.Server.Port),
+		Handler: r,
+	}
+	// Потоки событий закрываются сразу, чтобы Shutdown не ждал их до таймаута
+	srv.RegisterOnShutdown(background.CloseStreams)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("server listening on %s", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+
+	var failure error
+	select {
+	case sig := <-stop:
+		log.Printf("received %s, shutting down", sig)
+	case failure = <-serverErr:
+		log.Printf("server error: %v, shutting down", failure)
+	}
+
+	// Сначала дожидаемся текущих HTTP-запросов, чтобы они успели запустить свои фоновые задачи,
+	// затем останавливаем воркеры и ждем задачи; на все отводится SERVER_SHUTDOWN_TIMEOUT
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("failed to shut down http server: %v", err)
+	}
+	if err := background.Shutdown(shutdownCtx); err != nil {
+		log.Printf("failed to stop background tasks: %v", err)
+	}
+
+	if failure != nil {
+		return fmt.Errorf("http server failed: %w", failure)
+	}
+	log.Println("server stopped")
+	return nil
+}
+
+func runMigrate(ctx context.Context, migrator *migrate.Migrator, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: server migrate up|down [N]|status")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("applied migrations: %v", applied)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps: %s", args[1])
+			}
+			steps = n
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		log.Printf("reverted migrations: %v", reverted)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%03d_%s\t%s\n", status.Version, status.Name, applied)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q: expected up, down or status", args[0])
+	}
+
+	return nil
+}
+
+func runAPIKey(ctx context.Context, apiKeyService *service.APIKeyService, args []string) error {
+	if len(args) < 3 || len(args) > 4 || args[0] != "create" {
+		return fmt.Errorf("usage: server apikey create <name> <scope,scope,...> [tenant]")
+	}
+	if len(args) == 4 {
+		ctx = auth.WithTenant(ctx, args[3])
+	}
+
+	scopes := strings.Split(args[2], ",")
+	for _, scope := range scopes {
+		switch scope {
+		case auth.ScopeIncidentsRead, auth.ScopeIncidentsWrite, auth.ScopeStatsRead, auth.ScopeAdmin:
+		default:
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+
+	issued, err := apiKeyService.Issue(ctx, models.CreateAPIKeyRequest{Name: args[1], Scopes: scopes})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("id: %s\nkey: %s\n", issued.ID, issued.Key)
+	return nil
+}
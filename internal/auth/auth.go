@@ -0,0 +1,114 @@
+// Package auth описывает ключи API, их области доступа и субъекта запроса
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"geo_system_core/internal/models"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ScopeIncidentsRead  = "incidents:read"
+	ScopeIncidentsWrite = "incidents:write"
+	ScopeStatsRead      = "stats:read"
+	ScopeAdmin          = "admin" // включает все остальные области
+)
+
+// ErrUnauthorized — ключ отсутствует, неизвестен, отозван или истек
+var ErrUnauthorized = errors.New("invalid or missing API key")
+
+// keyPrefix отличает ключи сервиса от других секретов, например при поиске утечек
+const keyPrefix = "gsk"
+
+// Principal — субъект запроса, прошедший аутентификацию
+type Principal struct {
+	KeyID    uuid.UUID // uuid.Nil — начальный ключ из API_KEY
+	TenantID string
+	Name     string
+	Scopes   []string
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPlatformAdmin — ключ admin арендатора default, которому доступны данные всех арендаторов
+func (p *Principal) IsPlatformAdmin() bool {
+	return p.TenantID == models.DefaultTenant && p.HasScope(ScopeAdmin)
+}
+
+type principalKey struct{}
+
+type tenantKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext возвращает субъекта запроса; nil — запрос без аутентификации
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext возвращает арендатора запроса; без арендатора в контексте — default
+func TenantFromContext(ctx context.Context) string {
+	if tenantID, ok := ctx.Value(tenantKey{}).(string); ok && tenantID != "" {
+		return tenantID
+	}
+	return models.DefaultTenant
+}
+
+// GenerateKey создает ключ вида gsk_<prefix>_<secret> и возвращает его вместе с prefix и хешем для хранения.
+// Сам ключ нигде не сохраняется и показывается только при выпуске.
+func GenerateKey() (key, prefix, hash string, err error) {
+	prefixBytes := make([]byte, 6)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", "", err
+	}
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", "", err
+	}
+
+	prefix = hex.EncodeToString(prefixBytes)
+	key = keyPrefix + "_" + prefix + "_" + base64.RawURLEncoding.EncodeToString(secretBytes)
+	return key, prefix, HashKey(key), nil
+}
+
+// ParsePrefix извлекает открытую часть ключа; ok == false, если ключ не в формате сервиса
+func ParsePrefix(key string) (prefix string, ok bool) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != keyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// HashKey возвращает SHA-256 ключа в hex. Ключи случайные и длинные, поэтому медленный хеш не нужен.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Equal сравнивает строки за постоянное время
+func Equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
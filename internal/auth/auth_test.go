@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"geo_system_core/internal/models"
+	"testing"
+)
+
+func TestGenerateKey(t *testing.T) {
+	key, prefix, hash, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	parsed, ok := ParsePrefix(key)
+	if !ok || parsed != prefix {
+		t.Errorf("ParsePrefix() = %s, %v, expected %s, true", parsed, ok, prefix)
+	}
+	if !Equal(HashKey(key), hash) {
+		t.Errorf("HashKey() does not match the generated hash")
+	}
+}
+
+func TestParsePrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		expected string
+		ok       bool
+	}{
+		{name: "Ключ сервиса", key: "gsk_0a1b2c3d4e5f_secret", expected: "0a1b2c3d4e5f", ok: true},
+		{name: "Секрет с подчеркиванием", key: "gsk_abc_se_cret", expected: "abc", ok: true},
+		{name: "Чужой префикс", key: "sk_abc_secret", ok: false},
+		{name: "Нет секрета", key: "gsk_abc_", ok: false},
+		{name: "Произвольная строка", key: "default-api-key", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix, ok := ParsePrefix(tt.key)
+			if ok != tt.ok || prefix != tt.expected {
+				t.Errorf("ParsePrefix() = %s, %v, expected %s, %v", prefix, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestPrincipalHasScope(t *testing.T) {
+	reader := &Principal{Scopes: []string{ScopeIncidentsRead}}
+	admin := &Principal{Scopes: []string{ScopeAdmin}}
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		scope     string
+		expected  bool
+	}{
+		{name: "Есть область", principal: reader, scope: ScopeIncidentsRead, expected: true},
+		{name: "Нет области", principal: reader, scope: ScopeIncidentsWrite, expected: false},
+		{name: "admin включает все области", principal: admin, scope: ScopeStatsRead, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.principal.HasScope(tt.scope); got != tt.expected {
+				t.Errorf("HasScope(%s) = %v, expected %v", tt.scope, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPrincipalIsPlatformAdmin(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *Principal
+		expected  bool
+	}{
+		{name: "admin арендатора default", principal: &Principal{TenantID: models.DefaultTenant, Scopes: []string{ScopeAdmin}}, expected: true},
+		{name: "admin другого арендатора", principal: &Principal{TenantID: "north", Scopes: []string{ScopeAdmin}}, expected: false},
+		{name: "Ключ default без admin", principal: &Principal{TenantID: models.DefaultTenant, Scopes: []string{ScopeIncidentsWrite}}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.principal.IsPlatformAdmin(); got != tt.expected {
+				t.Errorf("IsPlatformAdmin() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTenantFromContext(t *testing.T) {
+	if got := TenantFromContext(context.Background()); got != models.DefaultTenant {
+		t.Errorf("TenantFromContext() without tenant = %s, expected %s", got, models.DefaultTenant)
+	}
+	if got := TenantFromContext(WithTenant(context.Background(), "north")); got != "north" {
+		t.Errorf("TenantFromContext() = %s, expected north", got)
+	}
+}
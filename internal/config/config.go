@@ -10,26 +10,34 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	Webhook  WebhookConfig
-	Stats    StatsConfig
-	Auth     AuthConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Redis     RedisConfig
+	Webhook   WebhookConfig
+	Stats     StatsConfig
+	Auth      AuthConfig
+	Scheduler SchedulerConfig
+	Geofence  GeofenceConfig
+	Index     IndexConfig
+	Events    EventsConfig
+	Tracking  TrackingConfig
+	Location  LocationConfig
 }
 
 type ServerConfig struct {
-	Port string
-	Host string
+	Port            string
+	Host            string
+	ShutdownTimeout time.Duration // время на завершение запросов и фоновых задач при остановке
 }
 
 type DatabaseConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+	Host           string
+	Port           string
+	User           string
+	Password       string
+	DBName         string
+	SSLMode        string
+	MigrateOnStart bool // применять встроенные миграции при старте сервера
 }
 
 type RedisConfig struct {
@@ -40,10 +48,15 @@ type RedisConfig struct {
 }
 
 type WebhookConfig struct {
-	URL           string
-	RetryAttempts int
-	RetryDelay    time.Duration
-	Timeout       time.Duration
+	URL                  string // адрес, получающий все события помимо подписок; пустой — не используется
+	RetryAttempts        int
+	RetryDelay           time.Duration
+	Timeout              time.Duration
+	Secret               string        // секрет для подписи доставок HMAC-SHA256; пустой — без подписи
+	PreviousSecret       string        // предыдущий секрет, которым доставки подписываются на время смены ключа
+	SubscriptionsRefresh time.Duration // как часто воркер перечитывает подписки из БД
+	VisibilityTimeout    time.Duration // через сколько неподтвержденное событие возвращается в очередь
+	ReaperInterval       time.Duration // период поиска неподтвержденных событий
 }
 
 type StatsConfig struct {
@@ -51,7 +64,37 @@ type StatsConfig struct {
 }
 
 type AuthConfig struct {
-	APIKey string
+	APIKey string // начальный ключ с областью admin для выпуска ключей через API; пустой — отключен
+}
+
+type GeofenceConfig struct {
+	DwellTime time.Duration // через сколько после входа отправлять zone.dwell; 0 — не отправлять
+	StateTTL  time.Duration // сколько хранить набор зон пользователя без новых проверок
+}
+
+type IndexConfig struct {
+	RefreshInterval time.Duration // период перечитывания индекса инцидентов
+	CacheTTL        time.Duration // TTL общего снимка инцидентов в Redis
+}
+
+type EventsConfig struct {
+	StreamLength int           // сколько последних событий арендатора хранится для возобновления по Last-Event-ID
+	Heartbeat    time.Duration // период комментариев в потоке SSE, не дающих закрыть простаивающее соединение
+}
+
+type LocationConfig struct {
+	WarningDistance    float64 // дистанция предупреждения о приближении к зоне, если клиент ее не передал, м
+	MaxWarningDistance float64 // верхняя граница дистанции предупреждения из запроса, м
+	InsideConfidence   float64 // с какой вероятности нахождения в зоне точка считается в ней (inside, has_danger)
+	PossibleConfidence float64 // с какой вероятности зона возвращается как possibly_inside
+}
+
+type TrackingConfig struct {
+	NearbyDistance float64 // на каком расстоянии от границы зоны клиент отслеживания узнает о новом инциденте, м
+}
+
+type SchedulerConfig struct {
+	Interval time.Duration // период проверки истекших инцидентов
 }
 
 func Load() (*Config, error) {
@@ -60,16 +103,18 @@ func Load() (*Config, error) {
 
 	config := &Config{
 		Server: ServerConfig{
-			Host: getEnv("SERVER_HOST", "0.0.0.0"),
-			Port: getEnv("SERVER_PORT", "8080"),
+			Host:            getEnv("SERVER_HOST", "0.0.0.0"),
+			Port:            getEnv("SERVER_PORT", "8080"),
+			ShutdownTimeout: getEnvAsDuration("SERVER_SHUTDOWN_TIMEOUT", 30*time.Second),
 		},
 		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5432"),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", "postgres"),
-			DBName:   getEnv("DB_NAME", "geo_system"),
-			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+			Host:           getEnv("DB_HOST", "localhost"),
+			Port:           getEnv("DB_PORT", "5432"),
+			User:           getEnv("DB_USER", "postgres"),
+			Password:       getEnv("DB_PASSWORD", "postgres"),
+			DBName:         getEnv("DB_NAME", "geo_system"),
+			SSLMode:        getEnv("DB_SSLMODE", "disable"),
+			MigrateOnStart: getEnvAsBool("MIGRATE_ON_START", false),
 		},
 		Redis: RedisConfig{
 			Host:     getEnv("REDIS_HOST", "localhost"),
@@ -78,22 +123,94 @@ func Load() (*Config, error) {
 			DB:       getEnvAsInt("REDIS_DB", 0),
 		},
 		Webhook: WebhookConfig{
-			URL:           getEnv("WEBHOOK_URL", "http://localhost:9090/webhook"),
-			RetryAttempts: getEnvAsInt("WEBHOOK_RETRY_ATTEMPTS", 3),
-			RetryDelay:    getEnvAsDuration("WEBHOOK_RETRY_DELAY", 5*time.Second),
-			Timeout:       getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+			URL:                  getEnvAllowEmpty("WEBHOOK_URL", "http://localhost:9090/webhook"),
+			RetryAttempts:        getEnvAsInt("WEBHOOK_RETRY_ATTEMPTS", 3),
+			RetryDelay:           getEnvAsDuration("WEBHOOK_RETRY_DELAY", 5*time.Second),
+			Timeout:              getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+			Secret:               getEnv("WEBHOOK_SECRET", ""),
+			PreviousSecret:       getEnv("WEBHOOK_SECRET_PREVIOUS", ""),
+			SubscriptionsRefresh: getEnvAsDuration("WEBHOOK_SUBSCRIPTIONS_REFRESH", 30*time.Second),
+			VisibilityTimeout:    getEnvAsDuration("WEBHOOK_VISIBILITY_TIMEOUT", 5*time.Minute),
+			ReaperInterval:       getEnvAsDuration("WEBHOOK_REAPER_INTERVAL", 30*time.Second),
 		},
 		Stats: StatsConfig{
 			TimeWindowMinutes: getEnvAsInt("STATS_TIME_WINDOW_MINUTES", 60),
 		},
 		Auth: AuthConfig{
-			APIKey: getEnv("API_KEY", "default-api-key-change-in-production"),
+			APIKey: getEnv("API_KEY", ""),
+		},
+		Geofence: GeofenceConfig{
+			DwellTime: getEnvAsDuration("GEOFENCE_DWELL_TIME", 5*time.Minute),
+			StateTTL:  getEnvAsDuration("GEOFENCE_STATE_TTL", 24*time.Hour),
+		},
+		Index: IndexConfig{
+			RefreshInterval: getEnvAsDuration("INCIDENT_INDEX_REFRESH_INTERVAL", 30*time.Second),
+			CacheTTL:        getEnvAsDuration("INCIDENT_CACHE_TTL", 30*time.Second),
+		},
+		Scheduler: SchedulerConfig{
+			Interval: getEnvAsDuration("SCHEDULER_INTERVAL", 30*time.Second),
+		},
+		Events: EventsConfig{
+			StreamLength: getEnvAsInt("EVENTS_STREAM_LENGTH", 10000),
+			Heartbeat:    getEnvAsDuration("EVENTS_HEARTBEAT", 15*time.Second),
+		},
+		Location: LocationConfig{
+			WarningDistance:    getEnvAsFloat("LOCATION_WARNING_DISTANCE", 1000),
+			MaxWarningDistance: getEnvAsFloat("LOCATION_MAX_WARNING_DISTANCE", 10000),
+			InsideConfidence:   getEnvAsFloat("LOCATION_INSIDE_CONFIDENCE", 0.5),
+			PossibleConfidence: getEnvAsFloat("LOCATION_POSSIBLE_CONFIDENCE", 0.05),
+		},
+		Tracking: TrackingConfig{
+			NearbyDistance: getEnvAsFloat("TRACKING_NEARBY_DISTANCE", 5000),
 		},
 	}
 
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
 	return config, nil
 }
 
+// placeholderAPIKey — значение API_KEY из старых примеров конфигурации, известное всем
+const placeholderAPIKey = "default-api-key-change-in-production"
+
+// validate отклоняет значения, с которыми сервис не может безопасно работать
+func (c *Config) validate() error {
+	if c.Auth.APIKey == placeholderAPIKey {
+		return fmt.Errorf("API_KEY must not be the example value %q: set a random secret or leave it empty", placeholderAPIKey)
+	}
+	// Периоды фоновых задач: time.NewTicker паникует при неположительном значении
+	intervals := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"SCHEDULER_INTERVAL", c.Scheduler.Interval},
+		{"INCIDENT_INDEX_REFRESH_INTERVAL", c.Index.RefreshInterval},
+		{"WEBHOOK_REAPER_INTERVAL", c.Webhook.ReaperInterval},
+		{"EVENTS_HEARTBEAT", c.Events.Heartbeat},
+	}
+	for _, interval := range intervals {
+		if interval.value <= 0 {
+			return fmt.Errorf("%s must be positive, got %s", interval.name, interval.value)
+		}
+	}
+	if c.Webhook.RetryAttempts < 1 {
+		return fmt.Errorf("WEBHOOK_RETRY_ATTEMPTS must be at least 1, got %d", c.Webhook.RetryAttempts)
+	}
+	// Пока воркер доставляет событие, reaper не должен вернуть его в очередь: задержка перед попыткой n
+	// равна n*WEBHOOK_RETRY_DELAY, поэтому доставка длится не дольше N*timeout + delay*N*(N-1)/2
+	attempts := time.Duration(c.Webhook.RetryAttempts)
+	delivery := attempts*c.Webhook.Timeout + c.Webhook.RetryDelay*attempts*(attempts-1)/2
+	if c.Webhook.VisibilityTimeout <= delivery {
+		return fmt.Errorf("WEBHOOK_VISIBILITY_TIMEOUT must exceed the worst-case delivery time %s, got %s", delivery, c.Webhook.VisibilityTimeout)
+	}
+	// Зона possibly_inside должна требовать ненулевой уверенности и не перекрывать порог inside
+	if possible, inside := c.Location.PossibleConfidence, c.Location.InsideConfidence; !(possible > 0 && possible <= inside && inside <= 1) {
+		return fmt.Errorf("LOCATION_POSSIBLE_CONFIDENCE and LOCATION_INSIDE_CONFIDENCE must satisfy 0 < possible <= inside <= 1, got %g and %g", possible, inside)
+	}
+	return nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -101,6 +218,14 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvAllowEmpty в отличие от getEnv возвращает пустое значение, если переменная задана явно
+func getEnvAllowEmpty(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
 func getEnvAsInt(key string, defaultValue int) int {
 	valueStr := getEnv(key, "")
 	if value, err := strconv.Atoi(valueStr); err == nil {
@@ -109,6 +234,22 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
 func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	valueStr := getEnv(key, "")
 	if value, err := time.ParseDuration(valueStr); err == nil {
@@ -0,0 +1,44 @@
+package config
+
+import "testing"
+
+func TestLoadValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		value string
+		valid bool
+	}{
+		{name: "Без начального ключа", key: "API_KEY", value: "", valid: true},
+		{name: "Начальный ключ из старых примеров", key: "API_KEY", value: placeholderAPIKey, valid: false},
+		{name: "Нулевой период планировщика", key: "SCHEDULER_INTERVAL", value: "0s", valid: false},
+		{name: "Отрицательный период планировщика", key: "SCHEDULER_INTERVAL", value: "-1m", valid: false},
+		{name: "Нулевой период перечитывания индекса", key: "INCIDENT_INDEX_REFRESH_INTERVAL", value: "0s", valid: false},
+		{name: "Отрицательный период поиска неподтвержденных событий", key: "WEBHOOK_REAPER_INTERVAL", value: "-30s", valid: false},
+		{name: "Нулевой период heartbeat", key: "EVENTS_HEARTBEAT", value: "0s", valid: false},
+		{name: "Короткий период heartbeat", key: "EVENTS_HEARTBEAT", value: "1s", valid: true},
+		{name: "Одна попытка доставки", key: "WEBHOOK_RETRY_ATTEMPTS", value: "1", valid: true},
+		{name: "Без попыток доставки", key: "WEBHOOK_RETRY_ATTEMPTS", value: "0", valid: false},
+		{name: "Отрицательное число попыток", key: "WEBHOOK_RETRY_ATTEMPTS", value: "-2", valid: false},
+		{name: "Нулевой срок обработки вебхука", key: "WEBHOOK_VISIBILITY_TIMEOUT", value: "0s", valid: false},
+		{name: "Срок обработки короче доставки", key: "WEBHOOK_VISIBILITY_TIMEOUT", value: "45s", valid: false},
+		{name: "Срок обработки длиннее доставки", key: "WEBHOOK_VISIBILITY_TIMEOUT", value: "1m", valid: true},
+		{name: "Доставка длиннее срока обработки", key: "WEBHOOK_RETRY_ATTEMPTS", value: "20", valid: false},
+		{name: "Порог inside равен единице", key: "LOCATION_INSIDE_CONFIDENCE", value: "1", valid: true},
+		{name: "Порог inside больше единицы", key: "LOCATION_INSIDE_CONFIDENCE", value: "1.5", valid: false},
+		{name: "Порог inside ниже порога possibly_inside", key: "LOCATION_INSIDE_CONFIDENCE", value: "0.01", valid: false},
+		{name: "Нулевой порог possibly_inside", key: "LOCATION_POSSIBLE_CONFIDENCE", value: "0", valid: false},
+		{name: "Отрицательный порог possibly_inside", key: "LOCATION_POSSIBLE_CONFIDENCE", value: "-0.1", valid: false},
+		{name: "Порог possibly_inside равен порогу inside", key: "LOCATION_POSSIBLE_CONFIDENCE", value: "0.5", valid: true},
+		{name: "Порог possibly_inside выше порога inside", key: "LOCATION_POSSIBLE_CONFIDENCE", value: "0.6", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.key, tt.value)
+			if _, err := Load(); (err == nil) != tt.valid {
+				t.Errorf("Load() with %s=%q error = %v, expected valid: %v", tt.key, tt.value, err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"geo_system_core/internal/models"
+	"geo_system_core/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeadLetterHandler struct {
+	service *service.DeadLetterService
+}
+
+func NewDeadLetterHandler(service *service.DeadLetterService) *DeadLetterHandler {
+	return &DeadLetterHandler{service: service}
+}
+
+func (h *DeadLetterHandler) List(c *gin.Context) {
+	var params models.PaginationParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.Limit < 1 {
+		params.Limit = 10
+	}
+
+	deadLetters, total, err := h.service.List(c.Request.Context(), params.Page, params.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Data:       deadLetters,
+		Page:       params.Page,
+		Limit:      params.Limit,
+		Total:      total,
+		TotalPages: (total + params.Limit - 1) / params.Limit,
+	})
+}
+
+func (h *DeadLetterHandler) GetByID(c *gin.Context) {
+	id := c.Param("id")
+
+	deadLetter, err := h.service.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if err.Error() == "dead letter not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, deadLetter)
+}
+
+func (h *DeadLetterHandler) Replay(c *gin.Context) {
+	id := c.Param("id")
+
+	err := h.service.Replay(c.Request.Context(), id)
+	if err != nil {
+		if err.Error() == "dead letter not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrReplayTargetGone) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "delivery requeued"})
+}
+
+func (h *DeadLetterHandler) ReplayAll(c *gin.Context) {
+	response, err := h.service.ReplayAll(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, response)
+}
+
+func (h *DeadLetterHandler) Delete(c *gin.Context) {
+	id := c.Param("id")
+
+	err := h.service.Delete(c.Request.Context(), id)
+	if err != nil {
+		if err.Error() == "dead letter not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "dead letter deleted successfully"})
+}
+
+func (h *DeadLetterHandler) Purge(c *gin.Context) {
+	count, err := h.service.Purge(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"purged": count})
+}
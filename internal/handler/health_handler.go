@@ -1,15 +1,18 @@
 package handler
 
 import (
+	"geo_system_core/internal/service"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-type HealthHandler struct{}
+type HealthHandler struct {
+	service *service.HealthService
+}
 
-func NewHealthHandler() *HealthHandler {
-	return &HealthHandler{}
+func NewHealthHandler(service *service.HealthService) *HealthHandler {
+	return &HealthHandler{service: service}
 }
 
 func (h *HealthHandler) Health(c *gin.Context) {
@@ -18,3 +21,20 @@ func (h *HealthHandler) Health(c *gin.Context) {
 		"service": "geo_system_core",
 	})
 }
+
+// Live отвечает, пока процесс способен обрабатывать запросы; зависимости не проверяются,
+// чтобы недоступность БД не приводила к перезапуску всех подов
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Ready возвращает 503, если экземпляр не может обслуживать запросы
+func (h *HealthHandler) Ready(c *gin.Context) {
+	response, ready := h.service.Readiness(c.Request.Context())
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -1,9 +1,16 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"geo_system_core/internal/models"
 	"geo_system_core/internal/service"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -25,17 +32,40 @@ func (h *IncidentHandler) Create(c *gin.Context) {
 
 	incident, err := h.service.Create(c.Request.Context(), req)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidIncident) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	c.Header("ETag", incidentETag(incident.Version))
 	c.JSON(http.StatusCreated, toIncidentResponse(incident))
 }
 
+// GetByID возвращает инцидент и его версию в ETag; с параметром as_of (RFC 3339) — состояние
+// на этот момент без ETag, так как оно не может быть передано в If-Match
 func (h *IncidentHandler) GetByID(c *gin.Context) {
 	id := c.Param("id")
 
-	incident, err := h.service.GetByID(c.Request.Context(), id)
+	var (
+		incident *models.Incident
+		err      error
+	)
+	if asOf := c.Query("as_of"); asOf != "" {
+		at, parseErr := time.Parse(time.RFC3339, asOf)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "as_of must be an RFC 3339 timestamp"})
+			return
+		}
+		incident, err = h.service.GetByIDAsOf(c.Request.Context(), id, at)
+	} else {
+		incident, err = h.service.GetByID(c.Request.Context(), id)
+		if err == nil {
+			c.Header("ETag", incidentETag(incident.Version))
+		}
+	}
 	if err != nil {
 		if err.Error() == "incident not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -62,8 +92,18 @@ func (h *IncidentHandler) List(c *gin.Context) {
 		params.Limit = 10
 	}
 
-	incidents, total, err := h.service.List(c.Request.Context(), params.Page, params.Limit)
+	var filter models.IncidentFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	incidents, total, next, err := h.service.List(c.Request.Context(), filter, params.Cursor, params.Page, params.Limit)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidFilter) || errors.Is(err, service.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -74,12 +114,152 @@ func (h *IncidentHandler) List(c *gin.Context) {
 		responses[i] = toIncidentResponse(&incident)
 	}
 
+	page := params.Page
+	if params.Cursor != "" {
+		page = 0 // номер страницы не определен при переходе по курсору
+	}
+
 	c.JSON(http.StatusOK, models.PaginatedResponse{
 		Data:       responses,
-		Page:       params.Page,
+		Page:       page,
 		Limit:      params.Limit,
 		Total:      total,
 		TotalPages: totalPages,
+		NextCursor: next,
+	})
+}
+
+// Events отдает поток изменений инцидентов арендатора (Server-Sent Events). После обрыва клиент
+// возобновляет чтение заголовком Last-Event-ID (или параметром last_event_id) и получает пропущенные события.
+func (h *IncidentHandler) Events(c *gin.Context) {
+	var filter models.IncidentEventFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	sub, err := h.service.SubscribeEvents(c.Request.Context(), filter, lastEventID)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidFilter) || errors.Is(err, service.ErrInvalidEventID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer sub.Close()
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no") // отключает буферизацию ответа в nginx
+	c.Status(http.StatusOK)
+
+	if sub.ResetID != "" {
+		if err := writeIncidentsReset(c.Writer, sub.ResetID); err != nil {
+			return
+		}
+	}
+	for _, event := range sub.Backlog {
+		if err := writeIncidentEvent(c.Writer, event); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(sub.Heartbeat())
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-sub.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events():
+			if !ok {
+				return // клиент отстал; переподключится с Last-Event-ID
+			}
+			if sub.Seen(event) {
+				continue
+			}
+			if err := writeIncidentEvent(c.Writer, event); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// writeIncidentEvent записывает событие в формате text/event-stream
+func writeIncidentEvent(w io.Writer, event models.IncidentEvent) error {
+	data, err := json.Marshal(models.IncidentEventData{
+		Event:     event.Event,
+		Incident:  toIncidentResponse(&event.Incident),
+		Timestamp: event.Timestamp,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Event, data)
+	return err
+}
+
+// writeIncidentsReset сообщает клиенту, что пропущенные события не будут отправлены и список инцидентов
+// нужно загрузить заново; id позволяет возобновить поток после последнего события
+func writeIncidentsReset(w io.Writer, id string) error {
+	data, err := json.Marshal(models.IncidentsResetData{
+		Event:     models.EventIncidentsReset,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", id, models.EventIncidentsReset, data)
+	return err
+}
+
+func (h *IncidentHandler) History(c *gin.Context) {
+	id := c.Param("id")
+
+	var params models.PaginationParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.Limit < 1 {
+		params.Limit = 10
+	}
+
+	entries, total, err := h.service.History(c.Request.Context(), id, params.Page, params.Limit)
+	if err != nil {
+		if err.Error() == "incident not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Data:       entries,
+		Page:       params.Page,
+		Limit:      params.Limit,
+		Total:      total,
+		TotalPages: (total + params.Limit - 1) / params.Limit,
 	})
 }
 
@@ -92,28 +272,41 @@ func (h *IncidentHandler) Update(c *gin.Context) {
 		return
 	}
 
-	incident, err := h.service.Update(c.Request.Context(), id, req)
+	incident, err := h.service.Update(c.Request.Context(), id, req, parseIfMatch(c.GetHeader("If-Match")))
 	if err != nil {
 		if err.Error() == "incident not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, service.ErrVersionMismatch) {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidIncident) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	c.Header("ETag", incidentETag(incident.Version))
 	c.JSON(http.StatusOK, toIncidentResponse(incident))
 }
 
 func (h *IncidentHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
 
-	err := h.service.Delete(c.Request.Context(), id)
+	err := h.service.Delete(c.Request.Context(), id, parseIfMatch(c.GetHeader("If-Match")))
 	if err != nil {
 		if err.Error() == "incident not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, service.ErrVersionMismatch) {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -121,6 +314,70 @@ func (h *IncidentHandler) Delete(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "incident deactivated successfully"})
 }
 
+func (h *IncidentHandler) Import(c *gin.Context) {
+	var params models.GeoJSONImportParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var fc models.FeatureCollection
+	if err := c.ShouldBindJSON(&fc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := h.service.ImportGeoJSON(c.Request.Context(), fc, params.Mode == "upsert")
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *IncidentHandler) Export(c *gin.Context) {
+	var params models.GeoJSONExportParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fc, err := h.service.ExportGeoJSON(c.Request.Context(), params.Circles == "polygon", params.Segments)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "application/geo+json")
+	c.JSON(http.StatusOK, fc)
+}
+
+// incidentETag — сильный ETag с версией инцидента
+func incidentETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseIfMatch возвращает версии из заголовка If-Match; nil — заголовка нет или передан "*".
+// If-Match требует строгого сравнения, поэтому слабые (W/) и нераспознанные метки пропускаются:
+// заголовок только из них не совпадет ни с одной версией.
+func parseIfMatch(header string) []int {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil
+	}
+
+	versions := []int{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		version, err := strconv.Atoi(tag[1 : len(tag)-1])
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	return versions
+}
+
 func toIncidentResponse(incident *models.Incident) models.IncidentResponse {
 	return models.IncidentResponse{
 		ID:          incident.ID,
@@ -129,9 +386,13 @@ func toIncidentResponse(incident *models.Incident) models.IncidentResponse {
 		Latitude:    incident.Latitude,
 		Longitude:   incident.Longitude,
 		Radius:      incident.Radius,
+		Geometry:    incident.Geometry,
 		Severity:    incident.Severity,
 		Status:      incident.Status,
 		IsActive:    incident.IsActive,
+		Version:     incident.Version,
+		StartsAt:    incident.StartsAt,
+		ExpiresAt:   incident.ExpiresAt,
 		CreatedAt:   incident.CreatedAt,
 		UpdatedAt:   incident.UpdatedAt,
 	}
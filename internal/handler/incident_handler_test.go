@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected []int
+	}{
+		{name: "Заголовка нет", header: "", expected: nil},
+		{name: "Любая версия", header: "*", expected: nil},
+		{name: "Одна версия", header: `"3"`, expected: []int{3}},
+		{name: "Несколько версий", header: `"3", "4"`, expected: []int{3, 4}},
+		{name: "Слабая метка не подходит", header: `W/"3"`, expected: []int{}},
+		{name: "Метка без кавычек не подходит", header: `3`, expected: []int{}},
+		{name: "Чужая метка пропускается", header: `"abc", "5"`, expected: []int{5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseIfMatch(tt.header); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parseIfMatch(%q) = %v, expected %v", tt.header, got, tt.expected)
+			}
+		})
+	}
+}
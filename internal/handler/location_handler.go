@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"geo_system_core/internal/models"
 	"geo_system_core/internal/service"
 	"net/http"
@@ -31,3 +32,74 @@ func (h *LocationHandler) Check(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+func (h *LocationHandler) CheckBatch(c *gin.Context) {
+	var req models.BatchLocationCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.service.CheckLocationBatch(c.Request.Context(), req.Items)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CheckRoute проверяет, пересекает ли маршрут зоны инцидентов
+func (h *LocationHandler) CheckRoute(c *gin.Context) {
+	var req models.RouteCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.service.CheckRoute(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRoute) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ListChecks возвращает журнал проверок координат от новых к старым; листается только курсором
+func (h *LocationHandler) ListChecks(c *gin.Context) {
+	var params models.PaginationParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if params.Limit < 1 {
+		params.Limit = 10
+	}
+
+	var filter models.LocationCheckFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	checks, next, err := h.service.ListChecks(c.Request.Context(), filter, params.Cursor, params.Limit)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) || errors.Is(err, service.ErrInvalidCheckFilter) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CursorPage{
+		Data:       checks,
+		Limit:      params.Limit,
+		NextCursor: next,
+	})
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"errors"
+	"geo_system_core/internal/models"
+	"geo_system_core/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SubscriptionHandler struct {
+	service *service.SubscriptionService
+}
+
+func NewSubscriptionHandler(service *service.SubscriptionService) *SubscriptionHandler {
+	return &SubscriptionHandler{service: service}
+}
+
+func (h *SubscriptionHandler) Create(c *gin.Context) {
+	var req models.CreateSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub, err := h.service.Create(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSubscription) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, sub)
+}
+
+func (h *SubscriptionHandler) GetByID(c *gin.Context) {
+	id := c.Param("id")
+
+	sub, err := h.service.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if err.Error() == "subscription not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+func (h *SubscriptionHandler) List(c *gin.Context) {
+	subs, err := h.service.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": subs})
+}
+
+func (h *SubscriptionHandler) Update(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.UpdateSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub, err := h.service.Update(c.Request.Context(), id, req)
+	if err != nil {
+		if err.Error() == "subscription not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidSubscription) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+func (h *SubscriptionHandler) Delete(c *gin.Context) {
+	id := c.Param("id")
+
+	err := h.service.Delete(c.Request.Context(), id)
+	if err != nil {
+		if err.Error() == "subscription not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "subscription deleted successfully"})
+}
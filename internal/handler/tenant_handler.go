@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"errors"
+	"geo_system_core/internal/models"
+	"geo_system_core/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TenantHandler struct {
+	service *service.TenantService
+}
+
+func NewTenantHandler(service *service.TenantService) *TenantHandler {
+	return &TenantHandler{service: service}
+}
+
+func (h *TenantHandler) Create(c *gin.Context) {
+	var req models.CreateTenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenant, err := h.service.Create(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTenant) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "tenant already exists" {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, tenant)
+}
+
+func (h *TenantHandler) List(c *gin.Context) {
+	tenants, err := h.service.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": tenants})
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"encoding/json"
+	"geo_system_core/internal/models"
+	"geo_system_core/internal/service"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	trackingWriteWait    = 10 * time.Second // на запись одного сообщения
+	trackingPongWait     = 60 * time.Second // сколько ждать ответа на ping, прежде чем считать клиента потерянным
+	trackingPingPeriod   = trackingPongWait * 9 / 10
+	trackingMaxMessage   = 4096 // байт в одном сообщении клиента
+	trackingInputBacklog = 8    // позиций, ожидающих проверки
+)
+
+// Ключ API передается заголовком, а не cookie, поэтому проверка Origin не защищает от подделки запроса
+var trackingUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+type TrackingHandler struct {
+	service *service.TrackingService
+}
+
+func NewTrackingHandler(service *service.TrackingService) *TrackingHandler {
+	return &TrackingHandler{service: service}
+}
+
+// trackingInput — позиция клиента или ошибка разбора его сообщения
+type trackingInput struct {
+	position models.TrackingPosition
+	err      error
+}
+
+// Track принимает позиции пользователя user_id по WebSocket и отправляет в то же соединение
+// изменения набора опасных зон и новые инциденты рядом с последней позицией
+func (h *TrackingHandler) Track(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	session, err := h.service.Start(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer session.Close()
+
+	conn, err := trackingUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return // Upgrade уже ответил клиенту
+	}
+	defer conn.Close()
+
+	inputs := make(chan trackingInput, trackingInputBacklog)
+	done := make(chan struct{})
+	defer close(done)
+	go readTrackingInputs(conn, inputs, done)
+
+	ping := time.NewTicker(trackingPingPeriod)
+	defer ping.Stop()
+
+	for {
+		var messages []models.TrackingMessage
+		select {
+		case <-session.Done():
+			closeTracking(conn, websocket.CloseGoingAway, "server is shutting down")
+			return
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(trackingWriteWait)); err != nil {
+				return
+			}
+			continue
+		case input, ok := <-inputs:
+			if !ok {
+				return // клиент закрыл соединение или перестал отвечать
+			}
+			if input.err != nil {
+				messages = append(messages, trackingError(input.err))
+				break
+			}
+			message, err := session.Position(ctx, input.position)
+			if err != nil {
+				messages = append(messages, trackingError(err))
+			} else if message != nil {
+				messages = append(messages, *message)
+			}
+		case event, ok := <-session.Events():
+			if !ok {
+				closeTracking(conn, websocket.CloseTryAgainLater, "client is too slow")
+				return
+			}
+			messages, err = session.Incident(ctx, event)
+			if err != nil {
+				log.Printf("tracking: %s: %v", event.Incident.ID, err)
+			}
+		}
+
+		for _, message := range messages {
+			conn.SetWriteDeadline(time.Now().Add(trackingWriteWait))
+			if err := conn.WriteJSON(message); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readTrackingInputs читает сообщения клиента, пока соединение открыто и обработчик не завершился (done);
+// единственный читатель соединения
+func readTrackingInputs(conn *websocket.Conn, inputs chan<- trackingInput, done <-chan struct{}) {
+	defer close(inputs)
+
+	conn.SetReadLimit(trackingMaxMessage)
+	conn.SetReadDeadline(time.Now().Add(trackingPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(trackingPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(trackingPongWait))
+
+		var input trackingInput
+		input.err = json.Unmarshal(data, &input.position)
+		select {
+		case inputs <- input:
+		case <-done:
+			return
+		}
+	}
+}
+
+func trackingError(err error) models.TrackingMessage {
+	return models.TrackingMessage{Type: models.TrackingMessageError, Error: err.Error(), Timestamp: time.Now()}
+}
+
+func closeTracking(conn *websocket.Conn, code int, reason string) {
+	message := websocket.FormatCloseMessage(code, reason)
+	_ = conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(trackingWriteWait))
+}
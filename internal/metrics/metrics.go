@@ -0,0 +1,69 @@
+// Package metrics содержит метрики Prometheus, которые отдаются на /metrics
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "geo_system"
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	LocationChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "location_checks_total",
+		Help:      "Checked locations by outcome (danger or safe).",
+	}, []string{"result"})
+
+	LocationQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "location_query_duration_seconds",
+		Help:      "Latency of looking up incidents for a location check by source (index or database).",
+		Buckets:   []float64{.0001, .0005, .001, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"source"})
+
+	ActiveIncidents = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "incidents_active",
+		Help:      "Incidents currently in effect by severity, as seen by the in-memory index.",
+	}, []string{"severity"})
+
+	WebhookQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "webhook_queue_depth",
+		Help:      "Webhook events waiting in the queue and being delivered.",
+	}, []string{"state"})
+
+	WebhookDeliveryAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_delivery_attempts_total",
+		Help:      "Webhook delivery attempts by outcome and response status code (none if no response).",
+	}, []string{"outcome", "status_code"})
+
+	WebhookRetriesExhausted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_retries_exhausted_total",
+		Help:      "Webhook deliveries moved to the dead-letter list after all attempts failed.",
+	})
+)
+
+// Handler отдает метрики в формате Prometheus
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
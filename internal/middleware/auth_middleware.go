@@ -1,24 +1,47 @@
 package middleware
 
 import (
+	"context"
+	"geo_system_core/internal/auth"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func APIKeyAuth(apiKey string) gin.HandlerFunc {
+type Authenticator interface {
+	Authenticate(ctx context.Context, key string) (*auth.Principal, error)
+}
+
+// RequireScope пропускает запросы с ключом в заголовке X-API-Key, которому выдана область scope,
+// и сохраняет субъекта и арендатора запроса в контексте. Ключ в строке запроса не принимается:
+// он попадает в логи прокси и историю браузера.
+func RequireScope(authenticator Authenticator, tenants TenantResolver, scope string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		key := c.GetHeader("X-API-Key")
-		if key == "" {
-			key = c.Query("api_key")
+		principal, err := authenticator.Authenticate(c.Request.Context(), c.GetHeader("X-API-Key"))
+		if err != nil {
+			abortAuthError(c, err)
+			return
+		}
+
+		if !principal.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "api key lacks scope " + scope})
+			return
 		}
 
-		if key != apiKey {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or missing API key"})
-			c.Abort()
+		if !setTenant(c, principal, tenants) {
 			return
 		}
+		c.Next()
+	}
+}
 
+// RequirePlatformAdmin пропускает только администраторов платформы; ставится после RequireScope
+func RequirePlatformAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if principal := auth.FromContext(c.Request.Context()); principal == nil || !principal.IsPlatformAdmin() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "api key is not a platform admin key"})
+			return
+		}
 		c.Next()
 	}
 }
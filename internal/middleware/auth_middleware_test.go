@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"geo_system_core/internal/auth"
+	"geo_system_core/internal/models"
+	"net/http"
+	"testing"
+)
+
+func TestRequireScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		tenant   string
+		scope    string
+		status   int
+		expected string
+	}{
+		{name: "Без ключа", scope: auth.ScopeIncidentsRead, status: http.StatusUnauthorized},
+		{name: "Неизвестный ключ", key: "wrong", scope: auth.ScopeIncidentsRead, status: http.StatusUnauthorized},
+		{name: "Нет области", key: "reader", scope: auth.ScopeIncidentsWrite, status: http.StatusForbidden},
+		{name: "Есть область", key: "reader", scope: auth.ScopeIncidentsRead, status: http.StatusOK, expected: "north"},
+		{name: "admin включает все области", key: "admin", scope: auth.ScopeStatsRead, status: http.StatusOK, expected: models.DefaultTenant},
+		{name: "Чужой арендатор в заголовке", key: "reader", tenant: "south", scope: auth.ScopeIncidentsRead, status: http.StatusForbidden},
+		{name: "Администратор платформы выбирает арендатора", key: "admin", tenant: "south", scope: auth.ScopeIncidentsRead, status: http.StatusOK, expected: "south"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, tenant := serve(t, RequireScope(testAuthenticator, testTenants, tt.scope), tt.key, tt.tenant)
+			if status != tt.status {
+				t.Fatalf("RequireScope() status = %d, expected %d", status, tt.status)
+			}
+			if tenant != tt.expected {
+				t.Errorf("RequireScope() tenant = %q, expected %q", tenant, tt.expected)
+			}
+		})
+	}
+}
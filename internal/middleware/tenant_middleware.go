@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"geo_system_core/internal/auth"
+	"geo_system_core/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantHeader — заголовок с идентификатором арендатора
+const TenantHeader = "X-Tenant-ID"
+
+type TenantResolver interface {
+	Exists(ctx context.Context, tenantID string) (bool, error)
+}
+
+// ResolveTenant определяет арендатора для публичных эндпоинтов: по ключу в X-API-Key, если он передан,
+// а без ключа — default. Неверный ключ отклоняется, а не игнорируется.
+func ResolveTenant(authenticator Authenticator, tenants TenantResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var principal *auth.Principal
+		if key := c.GetHeader("X-API-Key"); key != "" {
+			var err error
+			principal, err = authenticator.Authenticate(c.Request.Context(), key)
+			if err != nil {
+				abortAuthError(c, err)
+				return
+			}
+		}
+
+		if !setTenant(c, principal, tenants) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// setTenant сохраняет в контексте субъекта и арендатора запроса. Арендатор ключа может быть заменен
+// заголовком X-Tenant-ID только для администратора платформы; запрос без ключа с другим арендатором
+// в заголовке отклоняется. Возвращает false, если запрос прерван.
+func setTenant(c *gin.Context, principal *auth.Principal, tenants TenantResolver) bool {
+	ctx := c.Request.Context()
+	header := c.GetHeader(TenantHeader)
+
+	tenantID := models.DefaultTenant
+	if principal != nil {
+		tenantID = principal.TenantID
+		ctx = auth.WithPrincipal(ctx, principal)
+	}
+	if header != "" && header != tenantID {
+		if principal == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "api key is required to access tenant " + header})
+			return false
+		}
+		if !principal.IsPlatformAdmin() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "api key is not allowed to access tenant " + header})
+			return false
+		}
+
+		exists, err := tenants.Exists(ctx, header)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return false
+		}
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "unknown tenant " + header})
+			return false
+		}
+		tenantID = header
+	}
+
+	c.Request = c.Request.WithContext(auth.WithTenant(ctx, tenantID))
+	return true
+}
+
+func abortAuthError(c *gin.Context, err error) {
+	if errors.Is(err, auth.ErrUnauthorized) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"context"
+	"geo_system_core/internal/auth"
+	"geo_system_core/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fakeAuthenticator map[string]*auth.Principal
+
+func (f fakeAuthenticator) Authenticate(_ context.Context, key string) (*auth.Principal, error) {
+	if principal, ok := f[key]; ok {
+		return principal, nil
+	}
+	return nil, auth.ErrUnauthorized
+}
+
+type fakeTenants map[string]bool
+
+func (f fakeTenants) Exists(_ context.Context, tenantID string) (bool, error) {
+	return f[tenantID], nil
+}
+
+var (
+	testAuthenticator = fakeAuthenticator{
+		"admin":  {TenantID: models.DefaultTenant, Scopes: []string{auth.ScopeAdmin}},
+		"reader": {TenantID: "north", Scopes: []string{auth.ScopeIncidentsRead}},
+	}
+	testTenants = fakeTenants{models.DefaultTenant: true, "north": true, "south": true}
+)
+
+// serve выполняет запрос через middleware и возвращает код ответа и арендатора из контекста обработчика
+func serve(t *testing.T, middleware gin.HandlerFunc, key, tenant string) (int, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	var resolved string
+	r := gin.New()
+	r.GET("/", middleware, func(c *gin.Context) {
+		resolved = auth.TenantFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	if tenant != "" {
+		req.Header.Set(TenantHeader, tenant)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code, resolved
+}
+
+func TestResolveTenant(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		tenant   string
+		status   int
+		expected string
+	}{
+		{name: "Без ключа и заголовка", status: http.StatusOK, expected: models.DefaultTenant},
+		{name: "Без ключа с default в заголовке", tenant: models.DefaultTenant, status: http.StatusOK, expected: models.DefaultTenant},
+		{name: "Без ключа с чужим арендатором", tenant: "south", status: http.StatusUnauthorized},
+		{name: "Без ключа с неизвестным арендатором", tenant: "unknown", status: http.StatusUnauthorized},
+		{name: "Неверный ключ", key: "wrong", status: http.StatusUnauthorized},
+		{name: "Арендатор ключа", key: "reader", status: http.StatusOK, expected: "north"},
+		{name: "Ключ арендатора с чужим заголовком", key: "reader", tenant: "south", status: http.StatusForbidden},
+		{name: "Администратор платформы выбирает арендатора", key: "admin", tenant: "south", status: http.StatusOK, expected: "south"},
+		{name: "Администратор платформы и неизвестный арендатор", key: "admin", tenant: "unknown", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, tenant := serve(t, ResolveTenant(testAuthenticator, testTenants), tt.key, tt.tenant)
+			if status != tt.status {
+				t.Fatalf("ResolveTenant() status = %d, expected %d", status, tt.status)
+			}
+			if tenant != tt.expected {
+				t.Errorf("ResolveTenant() tenant = %q, expected %q", tenant, tt.expected)
+			}
+		})
+	}
+}
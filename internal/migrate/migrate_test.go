@@ -0,0 +1,80 @@
+package migrate
+
+import (
+	"geo_system_core/migrations"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    fstest.MapFS
+		expected []int64
+		wantErr  bool
+	}{
+		{
+			name: "Миграции сортируются по версии",
+			files: fstest.MapFS{
+				"010_b.up.sql":   {Data: []byte("SELECT 10")},
+				"002_a.up.sql":   {Data: []byte("SELECT 2")},
+				"002_a.down.sql": {Data: []byte("SELECT -2")},
+			},
+			expected: []int64{2, 10},
+		},
+		{
+			name: "Нет up-файла",
+			files: fstest.MapFS{
+				"001_a.down.sql": {Data: []byte("SELECT 1")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Неверное имя файла",
+			files: fstest.MapFS{
+				"init.sql": {Data: []byte("SELECT 1")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Разные имена у одной версии",
+			files: fstest.MapFS{
+				"001_a.up.sql":   {Data: []byte("SELECT 1")},
+				"001_b.down.sql": {Data: []byte("SELECT 1")},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loaded, err := Load(tt.files)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(loaded) != len(tt.expected) {
+				t.Fatalf("Load() returned %d migrations, expected %d", len(loaded), len(tt.expected))
+			}
+			for i, version := range tt.expected {
+				if loaded[i].Version != version {
+					t.Errorf("migration %d version = %d, expected %d", i, loaded[i].Version, version)
+				}
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	loaded, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for _, m := range loaded {
+		if m.Down == "" {
+			t.Errorf("migration %d_%s has no down file", m.Version, m.Name)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKey — ключ оператора. Сам ключ не хранится, только его хеш.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	TenantID   string     `json:"tenant_id" db:"tenant_id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	KeyHash    string     `json:"-" db:"key_hash"`
+	Scopes     []string   `json:"scopes" db:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+type CreateAPIKeyRequest struct {
+	TenantID  string     `json:"tenant_id"` // другой арендатор может указать только администратор платформы
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,oneof=incidents:read incidents:write stats:read admin"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// RotateAPIKeyRequest — выпуск нового ключа с теми же параметрами. Старый ключ действует
+// еще GracePeriodSeconds секунд, чтобы клиенты успели переключиться.
+type RotateAPIKeyRequest struct {
+	GracePeriodSeconds int `json:"grace_period_seconds" binding:"omitempty,min=0,max=2592000"`
+}
+
+// IssuedAPIKeyResponse — ответ на выпуск ключа; key возвращается один раз
+type IssuedAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
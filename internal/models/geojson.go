@@ -0,0 +1,44 @@
+package models
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+type FeatureCollection struct {
+	Type     string    `json:"type" binding:"required,eq=FeatureCollection"`
+	Features []Feature `json:"features" binding:"required,max=1000"`
+}
+
+// Feature — объект GeoJSON. Geometry хранится в исходном виде: при импорте
+// допускаются Point (окружность с radius в properties), Polygon и MultiPolygon.
+type Feature struct {
+	Type       string                 `json:"type"`
+	ID         interface{}            `json:"id,omitempty"`
+	Geometry   json.RawMessage        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type GeoJSONImportParams struct {
+	Mode string `form:"mode" binding:"omitempty,oneof=create upsert"` // upsert обновляет инциденты по id объекта
+}
+
+type GeoJSONExportParams struct {
+	Circles  string `form:"circles" binding:"omitempty,oneof=point polygon"` // как выгружать окружности
+	Segments int    `form:"segments" binding:"omitempty,min=8,max=360"`      // число вершин аппроксимирующего полигона
+}
+
+type ImportResult struct {
+	Index  int        `json:"index"`
+	ID     *uuid.UUID `json:"id,omitempty"`
+	Action string     `json:"action,omitempty"` // created, updated
+	Error  string     `json:"error,omitempty"`
+}
+
+type ImportResponse struct {
+	Created int            `json:"created"`
+	Updated int            `json:"updated"`
+	Failed  int            `json:"failed"`
+	Results []ImportResult `json:"results"`
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	GeometryPolygon      = "Polygon"
+	GeometryMultiPolygon = "MultiPolygon"
+)
+
+// Position — точка в порядке GeoJSON: [долгота, широта]
+type Position [2]float64
+
+func (p Position) Lng() float64 { return p[0] }
+func (p Position) Lat() float64 { return p[1] }
+
+// Ring — замкнутый контур (первая точка совпадает с последней)
+type Ring []Position
+
+// Polygon — первое кольцо задает внешний контур, остальные — «дыры»
+type Polygon []Ring
+
+// Geometry — полигональная геометрия зоны (GeoJSON Polygon или MultiPolygon).
+// Внутри всегда хранится как набор полигонов, Polygon — частный случай из одного элемента.
+type Geometry struct {
+	Type     string
+	Polygons []Polygon
+}
+
+type geometryJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+func (g Geometry) MarshalJSON() ([]byte, error) {
+	var coordinates interface{}
+	switch g.Type {
+	case GeometryPolygon:
+		if len(g.Polygons) > 0 {
+			coordinates = g.Polygons[0]
+		} else {
+			coordinates = Polygon{}
+		}
+	case GeometryMultiPolygon:
+		coordinates = g.Polygons
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %q", g.Type)
+	}
+
+	return json.Marshal(struct {
+		Type        string      `json:"type"`
+		Coordinates interface{} `json:"coordinates"`
+	}{g.Type, coordinates})
+}
+
+func (g *Geometry) UnmarshalJSON(data []byte) error {
+	var raw geometryJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	switch raw.Type {
+	case GeometryPolygon:
+		var polygon Polygon
+		if err := json.Unmarshal(raw.Coordinates, &polygon); err != nil {
+			return fmt.Errorf("invalid polygon coordinates: %w", err)
+		}
+		g.Polygons = []Polygon{polygon}
+	case GeometryMultiPolygon:
+		var polygons []Polygon
+		if err := json.Unmarshal(raw.Coordinates, &polygons); err != nil {
+			return fmt.Errorf("invalid multipolygon coordinates: %w", err)
+		}
+		g.Polygons = polygons
+	default:
+		return fmt.Errorf("unsupported geometry type %q: expected Polygon or MultiPolygon", raw.Type)
+	}
+
+	g.Type = raw.Type
+	return nil
+}
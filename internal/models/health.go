@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// DependencyStatus — результат проверки одной зависимости
+type DependencyStatus struct {
+	Status    string     `json:"status"`
+	LatencyMs float64    `json:"latency_ms"`
+	Error     string     `json:"error,omitempty"`
+	LastSeen  *time.Time `json:"last_seen,omitempty"` // для воркера — время последнего прохода цикла
+}
+
+type WebhookQueueDepth struct {
+	Queued     int64 `json:"queued"`
+	Processing int64 `json:"processing"`
+}
+
+// ReadinessResponse — состояние экземпляра: ok, degraded (работает без воркера вебхуков)
+// или unavailable (недоступна БД или Redis)
+type ReadinessResponse struct {
+	Status       string                      `json:"status"`
+	Service      string                      `json:"service"`
+	Checks       map[string]DependencyStatus `json:"checks"`
+	WebhookQueue *WebhookQueueDepth          `json:"webhook_queue,omitempty"`
+}
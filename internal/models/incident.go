@@ -1,68 +1,147 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type Incident struct {
-	ID          uuid.UUID `json:"id" db:"id"`
-	Title       string    `json:"title" db:"title"`
-	Description string    `json:"description" db:"description"`
-	Latitude    float64   `json:"latitude" db:"latitude"`
-	Longitude   float64   `json:"longitude" db:"longitude"`
-	Radius      float64   `json:"radius" db:"radius"`     // радиус в метрах
-	Severity    string    `json:"severity" db:"severity"` // low, medium, high, critical
-	Status      string    `json:"status" db:"status"`     // active, resolved
-	IsActive    bool      `json:"is_active" db:"is_active"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID          uuid.UUID  `json:"id" db:"id"`
+	TenantID    string     `json:"tenant_id" db:"tenant_id"`
+	Title       string     `json:"title" db:"title"`
+	Description string     `json:"description" db:"description"`
+	Latitude    float64    `json:"latitude" db:"latitude"`
+	Longitude   float64    `json:"longitude" db:"longitude"`
+	Radius      float64    `json:"radius" db:"radius"`     // радиус в метрах
+	Geometry    *Geometry  `json:"geometry" db:"geometry"` // полигон зоны; nil — зона-окружность
+	Severity    string     `json:"severity" db:"severity"` // low, medium, high, critical
+	Status      string     `json:"status" db:"status"`     // active, resolved
+	IsActive    bool       `json:"is_active" db:"is_active"`
+	Version     int        `json:"version" db:"version"`       // увеличивается при каждом изменении, передается в ETag
+	StartsAt    *time.Time `json:"starts_at" db:"starts_at"`   // начало действия; nil — сразу
+	ExpiresAt   *time.Time `json:"expires_at" db:"expires_at"` // окончание действия; nil — бессрочно
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
 }
 
+// CreateIncidentRequest описывает зону либо окружностью (latitude, longitude, radius — все обязательны),
+// либо полигональной геометрией. Для полигона центр и радиус описанной окружности вычисляются сервисом.
 type CreateIncidentRequest struct {
-	Title       string  `json:"title" binding:"required"`
-	Description string  `json:"description"`
-	Latitude    float64 `json:"latitude" binding:"required"`
-	Longitude   float64 `json:"longitude" binding:"required"`
-	Radius      float64 `json:"radius" binding:"required,gt=0"`
-	Severity    string  `json:"severity" binding:"required,oneof=low medium high critical"`
-	Status      string  `json:"status" binding:"oneof=active resolved"`
+	ID          uuid.UUID  `json:"-"` // задается только при импорте GeoJSON, иначе генерируется
+	Title       string     `json:"title" binding:"required"`
+	Description string     `json:"description"`
+	Latitude    *float64   `json:"latitude"`
+	Longitude   *float64   `json:"longitude"`
+	Radius      *float64   `json:"radius" binding:"omitempty,gt=0"`
+	Geometry    *Geometry  `json:"geometry"`
+	Severity    string     `json:"severity" binding:"required,oneof=low medium high critical"`
+	Status      string     `json:"status" binding:"oneof=active resolved"`
+	StartsAt    *time.Time `json:"starts_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
 }
 
 type UpdateIncidentRequest struct {
-	Title       *string  `json:"title"`
-	Description *string  `json:"description"`
-	Latitude    *float64 `json:"latitude"`
-	Longitude   *float64 `json:"longitude"`
-	Radius      *float64 `json:"radius"`
-	Severity    *string  `json:"severity" binding:"omitempty,oneof=low medium high critical"`
-	Status      *string  `json:"status" binding:"omitempty,oneof=active resolved"`
+	Title       *string      `json:"title"`
+	Description *string      `json:"description"`
+	Latitude    *float64     `json:"latitude"`
+	Longitude   *float64     `json:"longitude"`
+	Radius      *float64     `json:"radius" binding:"omitempty,gt=0"`
+	Geometry    *Geometry    `json:"geometry"`
+	Severity    *string      `json:"severity" binding:"omitempty,oneof=low medium high critical"`
+	Status      *string      `json:"status" binding:"omitempty,oneof=active resolved"`
+	StartsAt    OptionalTime `json:"starts_at"` // null снимает ограничение
+	ExpiresAt   OptionalTime `json:"expires_at"`
+}
+
+// OptionalTime — время в запросе на изменение: отличает null (сбросить значение) от отсутствующего поля (не менять)
+type OptionalTime struct {
+	Set   bool
+	Value *time.Time
+}
+
+func (t *OptionalTime) UnmarshalJSON(data []byte) error {
+	t.Set = true
+	if string(data) == "null" {
+		t.Value = nil
+		return nil
+	}
+	var value time.Time
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	t.Value = &value
+	return nil
 }
 
 type IncidentResponse struct {
-	ID          uuid.UUID `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Latitude    float64   `json:"latitude"`
-	Longitude   float64   `json:"longitude"`
-	Radius      float64   `json:"radius"`
-	Severity    string    `json:"severity"`
-	Status      string    `json:"status"`
-	IsActive    bool      `json:"is_active"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          uuid.UUID  `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Latitude    float64    `json:"latitude"`
+	Longitude   float64    `json:"longitude"`
+	Radius      float64    `json:"radius"`
+	Geometry    *Geometry  `json:"geometry,omitempty"`
+	Severity    string     `json:"severity"`
+	Status      string     `json:"status"`
+	IsActive    bool       `json:"is_active"`
+	Version     int        `json:"version"`
+	StartsAt    *time.Time `json:"starts_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
+// PaginationParams — страница по номеру (page) или по курсору из next_cursor предыдущего ответа;
+// при заданном cursor параметр page игнорируется
 type PaginationParams struct {
-	Page  int `form:"page" binding:"omitempty,min=1"`
-	Limit int `form:"limit" binding:"omitempty,min=1,max=100"`
+	Page   int    `form:"page" binding:"omitempty,min=1"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor string `form:"cursor"`
+}
+
+// Cursor — позиция последней записи страницы в порядке (created_at, id)
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
 }
 
+// IncidentFilter — фильтры и сортировка списка инцидентов. Важность передается повторением параметра
+// или через запятую. Окрестность точки (lat, lng, radius) отбирает зоны, граница которых ближе radius метров;
+// bbox — зоны, центр которых внутри прямоугольника.
+type IncidentFilter struct {
+	Severity    []string   `form:"severity"`
+	Status      string     `form:"status" binding:"omitempty,oneof=active resolved"`
+	CreatedFrom *time.Time `form:"created_from"`
+	CreatedTo   *time.Time `form:"created_to"`
+	UpdatedFrom *time.Time `form:"updated_from"`
+	UpdatedTo   *time.Time `form:"updated_to"`
+	MinLat      *float64   `form:"min_lat"`
+	MinLng      *float64   `form:"min_lng"`
+	MaxLat      *float64   `form:"max_lat"`
+	MaxLng      *float64   `form:"max_lng"`
+	Lat         *float64   `form:"lat"`
+	Lng         *float64   `form:"lng"`
+	Radius      *float64   `form:"radius" binding:"omitempty,gt=0"`
+	Query       string     `form:"q"`
+	Sort        string     `form:"sort" binding:"omitempty,oneof=created_at -created_at updated_at -updated_at severity -severity distance -distance"`
+}
+
+// PaginatedResponse — страница списка. NextCursor пуст на последней странице и при сортировке
+// не по времени создания; в режиме курсора page не возвращается.
 type PaginatedResponse struct {
 	Data       interface{} `json:"data"`
-	Page       int         `json:"page"`
+	Page       int         `json:"page,omitempty"`
 	Limit      int         `json:"limit"`
 	Total      int         `json:"total"`
 	TotalPages int         `json:"total_pages"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// CursorPage — страница журнала, который листается только курсором: без подсчета общего количества
+type CursorPage struct {
+	Data       interface{} `json:"data"`
+	Limit      int         `json:"limit"`
+	NextCursor string      `json:"next_cursor,omitempty"`
 }
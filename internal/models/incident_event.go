@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+const (
+	EventIncidentCreated  = "incident.created"  // инцидент создан
+	EventIncidentUpdated  = "incident.updated"  // инцидент изменен без завершения
+	EventIncidentResolved = "incident.resolved" // инцидент переведен в resolved вручную или по expires_at
+	EventIncidentDeleted  = "incident.deleted"  // инцидент деактивирован
+
+	// EventIncidentsReset — клиент пропустил больше событий, чем отдается при возобновлении,
+	// и должен заново загрузить список инцидентов
+	EventIncidentsReset = "incidents.reset"
+)
+
+// IncidentEvent — изменение инцидента для потока SSE. ID — идентификатор записи в потоке Redis
+// арендатора; по нему клиент возобновляет чтение через Last-Event-ID.
+type IncidentEvent struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	TenantID  string    `json:"tenant_id"`
+	Incident  Incident  `json:"incident"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// IncidentEventData — данные события в потоке SSE; идентификатор и тип передаются полями id и event
+type IncidentEventData struct {
+	Event     string           `json:"event"`
+	Incident  IncidentResponse `json:"incident"`
+	Timestamp time.Time        `json:"timestamp"`
+}
+
+// IncidentsResetData — данные события incidents.reset
+type IncidentsResetData struct {
+	Event     string    `json:"event"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// IncidentEventFilter — фильтры потока событий: важность (повторением параметра или через запятую)
+// и прямоугольник, в который попадает центр зоны; параметры прямоугольника задаются все вместе
+type IncidentEventFilter struct {
+	Severity []string `form:"severity"`
+	MinLat   *float64 `form:"min_lat"`
+	MinLng   *float64 `form:"min_lng"`
+	MaxLat   *float64 `form:"max_lat"`
+	MaxLng   *float64 `form:"max_lng"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Действия в истории инцидента
+const (
+	HistoryCreated       = "created"
+	HistoryUpdated       = "updated"
+	HistoryStatusChanged = "status_changed"
+	HistoryDeleted       = "deleted"
+	HistorySnapshot      = "snapshot" // исходное состояние инцидента, созданного до появления истории
+)
+
+// Actor — автор изменения: ключ API или фоновая задача (KeyID == nil)
+type Actor struct {
+	KeyID *uuid.UUID
+	Name  string
+}
+
+// IncidentHistoryEntry — версия инцидента со снимками до и после изменения.
+// Before пуст для created и snapshot.
+type IncidentHistoryEntry struct {
+	IncidentID uuid.UUID  `json:"incident_id"`
+	Version    int        `json:"version"`
+	Action     string     `json:"action"`
+	Before     *Incident  `json:"before"`
+	After      *Incident  `json:"after"`
+	ActorKeyID *uuid.UUID `json:"actor_key_id,omitempty"`
+	Actor      string     `json:"actor"`
+	ChangedAt  time.Time  `json:"changed_at"`
+}
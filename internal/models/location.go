@@ -6,41 +6,161 @@ import (
 	"github.com/google/uuid"
 )
 
+// LocationCheckRequest — точка для проверки. WarningDistance — на каком расстоянии от границы зоны
+// предупреждать о приближении, м (ограничивается настройкой сервиса). Heading (азимут движения в градусах
+// от севера) и Speed (м/с) необязательны и нужны только для оценки времени до входа в зону.
+// Accuracy — точность координат, м: радиус, в который точка попадает с вероятностью 68%
+// (так ее сообщают Android и iOS). Без нее координаты считаются точными.
 type LocationCheckRequest struct {
-	Latitude  float64 `json:"latitude" binding:"required"`
-	Longitude float64 `json:"longitude" binding:"required"`
-	UserID    string  `json:"user_id" binding:"required"`
+	Latitude        float64  `json:"latitude" binding:"required"`
+	Longitude       float64  `json:"longitude" binding:"required"`
+	UserID          string   `json:"user_id" binding:"required"`
+	WarningDistance *float64 `json:"warning_distance,omitempty"`
+	Heading         *float64 `json:"heading,omitempty"`
+	Speed           *float64 `json:"speed,omitempty"`
+	Accuracy        *float64 `json:"accuracy,omitempty"`
 }
 
+// BatchLocationCheckRequest — пакет проверок, возможно для разных пользователей.
+// Элементы валидируются по отдельности, ошибка одного не влияет на остальные.
+type BatchLocationCheckRequest struct {
+	Items []LocationCheckRequest `json:"items" binding:"required,min=1,max=1000"`
+}
+
+type BatchLocationCheckResult struct {
+	Index  int                    `json:"index"`
+	Result *LocationCheckResponse `json:"result,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+type BatchLocationCheckResponse struct {
+	Results []BatchLocationCheckResult `json:"results"`
+}
+
+// RouteCheckRequest — маршрут для проверки: точки по порядку или encoded polyline (формат Google,
+// точность polyline_precision знаков, по умолчанию 5). DepartureAt — время выезда; учитываются инциденты,
+// действующие в этот момент. По умолчанию — текущее время.
+type RouteCheckRequest struct {
+	Points            []RoutePoint `json:"points" binding:"omitempty,max=10000"`
+	Polyline          string       `json:"polyline"`
+	PolylinePrecision int          `json:"polyline_precision" binding:"omitempty,min=5,max=6"`
+	DepartureAt       *time.Time   `json:"departure_at"`
+}
+
+type RoutePoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// RouteIntersection — проход маршрута через зону: от точки входа до точки выхода. Если маршрут
+// пересекает зону несколько раз, каждый проход — отдельный элемент. Смещения отсчитываются
+// в метрах вдоль маршрута от его начала.
+type RouteIntersection struct {
+	IncidentID     uuid.UUID  `json:"incident_id"`
+	Title          string     `json:"title"`
+	Severity       string     `json:"severity"`
+	Entry          RoutePoint `json:"entry"`
+	Exit           RoutePoint `json:"exit"`
+	EntryOffset    float64    `json:"entry_offset"`
+	ExitOffset     float64    `json:"exit_offset"`
+	DistanceInside float64    `json:"distance_inside"`
+}
+
+// RouteCheckResponse — проходы через зоны в порядке следования по маршруту
+type RouteCheckResponse struct {
+	Intersections     []RouteIntersection `json:"intersections"`
+	FirstIntersection *RouteIntersection  `json:"first_intersection,omitempty"`
+	HasDanger         bool                `json:"has_danger"`
+	RouteLength       float64             `json:"route_length"`
+}
+
+// LocationCheckResponse — зоны, в которых точка находится или может находиться с учетом точности
+// (nearby_incidents), и зоны, к границе которых она ближе дистанции предупреждения (approaching),
+// от ближайшей к дальней. HasDanger — есть зона с классификацией inside.
 type LocationCheckResponse struct {
-	NearbyIncidents []NearbyIncident `json:"nearby_incidents"`
-	HasDanger       bool             `json:"has_danger"`
+	NearbyIncidents []NearbyIncident      `json:"nearby_incidents"`
+	Approaching     []ApproachingIncident `json:"approaching,omitempty"`
+	HasDanger       bool                  `json:"has_danger"`
+}
+
+// ApproachingIncident — зона рядом с точкой. BoundaryDistance — расстояние до ближайшей точки границы, м;
+// Bearing — азимут на нее в градусах от севера; TimeToEntry — оценка времени до входа в секундах,
+// если переданы направление и скорость и текущий курс ведет в зону.
+type ApproachingIncident struct {
+	NearbyIncident
+	BoundaryDistance float64  `json:"boundary_distance"`
+	Bearing          float64  `json:"bearing"`
+	TimeToEntry      *float64 `json:"time_to_entry,omitempty"`
 }
 
+const (
+	ZoneInside         = "inside"          // уверенность не ниже порога LOCATION_INSIDE_CONFIDENCE
+	ZonePossiblyInside = "possibly_inside" // точка может быть в зоне с учетом точности координат
+	ZoneOutside        = "outside"         // точка вне зоны
+)
+
+// NearbyIncident — зона инцидента относительно точки. Confidence — вероятность того, что точка
+// в зоне, с учетом точности координат (без точности — 0 или 1); Classification — ее оценка.
+// Оба поля заполняются только при проверке координат, в остальных сообщениях их нет.
 type NearbyIncident struct {
-	ID          uuid.UUID `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Latitude    float64   `json:"latitude"`
-	Longitude   float64   `json:"longitude"`
-	Radius      float64   `json:"radius"`
-	Severity    string    `json:"severity"`
-	Distance    float64   `json:"distance"` // расстояние в метрах
+	ID             uuid.UUID `json:"id"`
+	Title          string    `json:"title"`
+	Description    string    `json:"description"`
+	Latitude       float64   `json:"latitude"`
+	Longitude      float64   `json:"longitude"`
+	Radius         float64   `json:"radius"`
+	Geometry       *Geometry `json:"geometry,omitempty"`
+	Severity       string    `json:"severity"`
+	Distance       float64   `json:"distance"` // расстояние до центра зоны в метрах
+	Classification string    `json:"classification,omitempty"`
+	Confidence     *float64  `json:"confidence,omitempty"`
 }
 
 type LocationCheckLog struct {
-	ID        uuid.UUID `db:"id"`
-	UserID    string    `db:"user_id"`
-	Latitude  float64   `db:"latitude"`
-	Longitude float64   `db:"longitude"`
-	HasDanger bool      `db:"has_danger"`
-	CreatedAt time.Time `db:"created_at"`
+	ID        uuid.UUID `json:"id" db:"id"`
+	TenantID  string    `json:"tenant_id" db:"tenant_id"`
+	UserID    string    `json:"user_id" db:"user_id"`
+	Latitude  float64   `json:"latitude" db:"latitude"`
+	Longitude float64   `json:"longitude" db:"longitude"`
+	HasDanger bool      `json:"has_danger" db:"has_danger"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
 
+// LocationCheckFilter — фильтры журнала проверок координат
+type LocationCheckFilter struct {
+	UserID    string     `form:"user_id"`
+	HasDanger *bool      `form:"has_danger"`
+	From      *time.Time `form:"from"`
+	To        *time.Time `form:"to"`
+}
+
+const (
+	EventZoneEnter       = "zone.enter"       // пользователь вошел в зону
+	EventZoneExit        = "zone.exit"        // пользователь покинул зону
+	EventZoneDwell       = "zone.dwell"       // пользователь находится в зоне дольше порога задержки
+	EventIncidentExpired = "incident.expired" // срок действия инцидента истек, он переведен в resolved
+)
+
+// WebhookPayload — событие для отправки вебхуком. Для событий зоны заполняются user_id и zone
+// (для zone.exit в zone передаются только id, title и severity), для событий жизненного цикла —
+// incident, а координаты указывают на центр зоны.
 type WebhookPayload struct {
-	UserID    string           `json:"user_id"`
-	Latitude  float64          `json:"latitude"`
-	Longitude float64          `json:"longitude"`
-	Timestamp time.Time        `json:"timestamp"`
-	Incidents []NearbyIncident `json:"incidents"`
+	Event     string          `json:"event"`
+	TenantID  string          `json:"tenant_id,omitempty"` // пустой у событий, поставленных до появления арендаторов — default
+	UserID    string          `json:"user_id,omitempty"`
+	Latitude  float64         `json:"latitude"`
+	Longitude float64         `json:"longitude"`
+	Timestamp time.Time       `json:"timestamp"`
+	Zone      *NearbyIncident `json:"zone,omitempty"`
+	EnteredAt *time.Time      `json:"entered_at,omitempty"` // время входа в зону для zone.exit и zone.dwell
+	Incident  *Incident       `json:"incident,omitempty"`
+}
+
+// ZoneState — сохраненное пребывание пользователя в зоне, по которому вычисляются события входа и выхода
+type ZoneState struct {
+	IncidentID uuid.UUID `json:"incident_id"`
+	Title      string    `json:"title"`
+	Severity   string    `json:"severity"`
+	EnteredAt  time.Time `json:"entered_at"`
+	DwellSent  bool      `json:"dwell_sent"`
 }
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BoundingBox — прямоугольная область в градусах
+type BoundingBox struct {
+	MinLat float64 `json:"min_lat"`
+	MinLng float64 `json:"min_lng"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLng float64 `json:"max_lng"`
+}
+
+func (b BoundingBox) Contains(lat, lng float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lng >= b.MinLng && lng <= b.MaxLng
+}
+
+// WebhookSubscription — получатель вебхуков со своими фильтрами.
+// Пустой EventTypes означает все типы событий, отсутствие BBox и Geometry — любую область.
+type WebhookSubscription struct {
+	ID          uuid.UUID    `json:"id" db:"id"`
+	TenantID    string       `json:"tenant_id" db:"tenant_id"`
+	URL         string       `json:"url" db:"url"`
+	Secret      string       `json:"-" db:"secret"`
+	MinSeverity string       `json:"min_severity" db:"min_severity"`
+	BBox        *BoundingBox `json:"bbox,omitempty" db:"bbox"`
+	Geometry    *Geometry    `json:"geometry,omitempty" db:"geometry"`
+	EventTypes  []string     `json:"event_types" db:"event_types"`
+	IsActive    bool         `json:"is_active" db:"is_active"`
+	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+type CreateSubscriptionRequest struct {
+	URL         string       `json:"url" binding:"required,url"`
+	Secret      string       `json:"secret"`
+	MinSeverity string       `json:"min_severity" binding:"omitempty,oneof=low medium high critical"`
+	BBox        *BoundingBox `json:"bbox"`
+	Geometry    *Geometry    `json:"geometry"`
+	EventTypes  []string     `json:"event_types" binding:"omitempty,dive,oneof=zone.enter zone.exit zone.dwell incident.expired"`
+}
+
+type UpdateSubscriptionRequest struct {
+	URL         *string      `json:"url" binding:"omitempty,url"`
+	Secret      *string      `json:"secret"`
+	MinSeverity *string      `json:"min_severity" binding:"omitempty,oneof=low medium high critical"`
+	BBox        *BoundingBox `json:"bbox"`
+	Geometry    *Geometry    `json:"geometry"`
+	EventTypes  *[]string    `json:"event_types" binding:"omitempty,dive,oneof=zone.enter zone.exit zone.dwell incident.expired"`
+	IsActive    *bool        `json:"is_active"`
+}
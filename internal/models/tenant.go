@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// DefaultTenant — арендатор, к которому относятся данные, созданные до появления арендаторов,
+// и запросы без указания арендатора
+const DefaultTenant = "default"
+
+// Tenant — арендатор (муниципалитет), данные которого изолированы от остальных
+type Tenant struct {
+	ID        string    `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type CreateTenantRequest struct {
+	ID   string `json:"id" binding:"required,max=63"`
+	Name string `json:"name" binding:"required"`
+}
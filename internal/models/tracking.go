@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+const (
+	TrackingMessageState    = "state"    // изменился набор зон, в которых находится клиент
+	TrackingMessageIncident = "incident" // рядом с последней позицией появился новый инцидент
+	TrackingMessageError    = "error"    // сообщение клиента не принято; соединение остается открытым
+)
+
+// TrackingPosition — позиция, которую клиент отправляет по WebSocket; Accuracy — как в LocationCheckRequest
+type TrackingPosition struct {
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	Accuracy  *float64 `json:"accuracy,omitempty"`
+}
+
+// TrackingMessage — сообщение сервера клиенту отслеживания. Для state заполняется State,
+// для incident — Incident (distance — расстояние от последней позиции до центра зоны), для error — Error.
+type TrackingMessage struct {
+	Type      string                 `json:"type"`
+	State     *LocationCheckResponse `json:"state,omitempty"`
+	Incident  *NearbyIncident        `json:"incident,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
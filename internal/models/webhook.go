@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookJob — элемент очереди вебхуков. У повторной отправки из dead-letter задан SubscriptionID:
+// событие доставляется только этому получателю (uuid.Nil — адрес из WEBHOOK_URL).
+type WebhookJob struct {
+	ID             uuid.UUID      `json:"id"` // делает одинаковые события различимыми в списке обработки
+	Payload        WebhookPayload `json:"payload"`
+	SubscriptionID *uuid.UUID     `json:"subscription_id,omitempty"`
+	Raw            string         `json:"-"` // элемент очереди в исходном виде, по нему подтверждается обработка
+}
+
+// DeadLetter — доставка, для которой исчерпаны все попытки отправки
+type DeadLetter struct {
+	ID             uuid.UUID      `json:"id"`
+	TenantID       string         `json:"tenant_id"`
+	SubscriptionID uuid.UUID      `json:"subscription_id"` // uuid.Nil — адрес из WEBHOOK_URL
+	URL            string         `json:"url"`
+	Payload        WebhookPayload `json:"payload"`
+	LastError      string         `json:"last_error"`
+	StatusCode     int            `json:"status_code,omitempty"` // 0 — ответ не получен
+	Attempts       int            `json:"attempts"`
+	FailedAt       time.Time      `json:"failed_at"`
+}
+
+type ReplayResponse struct {
+	Replayed int      `json:"replayed"`
+	Failed   []string `json:"failed,omitempty"` // ID доставок, получатель которых удален
+}
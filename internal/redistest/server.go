@@ -0,0 +1,739 @@
+// Package redistest запускает в памяти процесса сервер только с теми командами Redis, которые
+// отправляют репозитории в тестах: строки, списки, sorted set, hash, потоки и MULTI/EXEC с WATCH.
+// Время жизни ключей, блокирующее ожидание и pub/sub не поддерживаются: BLMOVE с пустым источником
+// сразу возвращает nil, а PUBLISH никому не доставляет сообщение.
+package redistest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type zmember struct {
+	member string
+	score  float64
+}
+
+// Server — сервер с общими для всех соединений данными
+type Server struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	strings  map[string]string
+	lists    map[string][]string
+	zsets    map[string]map[string]float64
+	hashes   map[string]map[string]string
+	streams  map[string][]streamEntry
+	versions map[string]uint64 // счетчик изменений ключа для WATCH
+}
+
+// NewServer запускает сервер и останавливает его по завершении теста
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start fake redis: %v", err)
+	}
+
+	s := &Server{
+		listener: listener,
+		strings:  make(map[string]string),
+		lists:    make(map[string][]string),
+		zsets:    make(map[string]map[string]float64),
+		hashes:   make(map[string]map[string]string),
+		streams:  make(map[string][]streamEntry),
+		versions: make(map[string]uint64),
+	}
+	go s.serve()
+	t.Cleanup(func() { _ = listener.Close() })
+
+	return s
+}
+
+// Addr — адрес сервера для redis.Options
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// NewClient возвращает клиент, подключенный к серверу и закрываемый по завершении теста
+func (s *Server) NewClient(t testing.TB) *redis.Client {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: s.Addr(), Protocol: 2, DisableIndentity: true})
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// List возвращает копию списка
+func (s *Server) List(key string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.lists[key]...)
+}
+
+// ZScore возвращает вес элемента sorted set; ok == false, если элемента нет
+func (s *Server) ZScore(key, member string) (score float64, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	score, ok = s.zsets[key][member]
+	return score, ok
+}
+
+// SetZScore меняет вес элемента sorted set, например чтобы сделать срок уже истекшим
+func (s *Server) SetZScore(key, member string, score float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.zsets[key] == nil {
+		s.zsets[key] = make(map[string]float64)
+	}
+	s.zsets[key][member] = score
+	s.versions[key]++
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// session — состояние соединения: очередь команд MULTI и версии ключей на момент WATCH
+type session struct {
+	multi   bool
+	queued  [][]string
+	watched map[string]uint64
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	sess := &session{}
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		s.dispatch(sess, args, w)
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) dispatch(sess *session, args []string, w *bufio.Writer) {
+	name := strings.ToUpper(args[0])
+
+	switch name {
+	case "MULTI":
+		sess.multi, sess.queued = true, nil
+		writeSimple(w, "OK")
+		return
+	case "WATCH":
+		s.mu.Lock()
+		if sess.watched == nil {
+			sess.watched = make(map[string]uint64)
+		}
+		for _, key := range args[1:] {
+			sess.watched[key] = s.versions[key]
+		}
+		s.mu.Unlock()
+		writeSimple(w, "OK")
+		return
+	case "UNWATCH":
+		sess.watched = nil
+		writeSimple(w, "OK")
+		return
+	case "EXEC":
+		s.exec(sess, w)
+		return
+	}
+
+	if sess.multi {
+		sess.queued = append(sess.queued, args)
+		writeSimple(w, "QUEUED")
+		return
+	}
+
+	s.mu.Lock()
+	reply := s.run(args)
+	s.mu.Unlock()
+	reply.write(w)
+}
+
+func (s *Server) exec(sess *session, w *bufio.Writer) {
+	queued, watched := sess.queued, sess.watched
+	sess.multi, sess.queued, sess.watched = false, nil, nil
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, version := range watched {
+		if s.versions[key] != version {
+			w.WriteString("*-1\r\n")
+			return
+		}
+	}
+
+	fmt.Fprintf(w, "*%d\r\n", len(queued))
+	for _, args := range queued {
+		s.run(args).write(w)
+	}
+}
+
+type handler func(s *Server, args []string) reply
+
+var commands map[string]handler
+
+func init() {
+	commands = map[string]handler{
+		"HELLO":         func(*Server, []string) reply { return errorReply("ERR unknown command 'HELLO'") }, // клиент переходит на RESP2
+		"GET":           (*Server).get,
+		"SET":           (*Server).set,
+		"DEL":           (*Server).del,
+		"INCR":          (*Server).incr,
+		"LPUSH":         (*Server).push,
+		"RPUSH":         (*Server).push,
+		"LRANGE":        (*Server).lrange,
+		"LREM":          (*Server).lrem,
+		"LLEN":          (*Server).llen,
+		"LPOS":          (*Server).lpos,
+		"BLMOVE":        (*Server).blmove,
+		"ZADD":          (*Server).zadd,
+		"ZREM":          (*Server).zrem,
+		"ZCARD":         (*Server).zcard,
+		"ZRANGEBYSCORE": (*Server).zrangeByScore,
+		"ZREVRANGE":     (*Server).zrevrange,
+		"HSET":          (*Server).hset,
+		"HMGET":         (*Server).hmget,
+		"HGETALL":       (*Server).hgetall,
+		"EXPIRE":        (*Server).expire,
+		"XADD":          (*Server).xadd,
+		"XRANGE":        (*Server).xrange,
+		"XREVRANGE":     (*Server).xrange,
+		"PUBLISH":       func(*Server, []string) reply { return integer(0) },
+	}
+}
+
+// run выполняет команду; вызывается под s.mu
+func (s *Server) run(args []string) reply {
+	h, ok := commands[strings.ToUpper(args[0])]
+	if !ok {
+		return errorReply("ERR unknown command '" + args[0] + "'")
+	}
+	return h(s, args)
+}
+
+func (s *Server) touch(key string) {
+	s.versions[key]++
+}
+
+func (s *Server) get(args []string) reply {
+	if value, ok := s.strings[args[1]]; ok {
+		return bulk(value)
+	}
+	return nullBulk()
+}
+
+func (s *Server) set(args []string) reply {
+	s.strings[args[1]] = args[2]
+	s.touch(args[1])
+	return simple("OK")
+}
+
+func (s *Server) exists(key string) bool {
+	_, isString := s.strings[key]
+	_, isList := s.lists[key]
+	_, isZSet := s.zsets[key]
+	_, isHash := s.hashes[key]
+	_, isStream := s.streams[key]
+	return isString || isList || isZSet || isHash || isStream
+}
+
+func (s *Server) del(args []string) reply {
+	deleted := 0
+	for _, key := range args[1:] {
+		if s.exists(key) {
+			deleted++
+		}
+		delete(s.strings, key)
+		delete(s.lists, key)
+		delete(s.zsets, key)
+		delete(s.hashes, key)
+		delete(s.streams, key)
+		s.touch(key)
+	}
+	return integer(deleted)
+}
+
+func (s *Server) incr(args []string) reply {
+	value, _ := strconv.Atoi(s.strings[args[1]])
+	value++
+	s.strings[args[1]] = strconv.Itoa(value)
+	s.touch(args[1])
+	return integer(value)
+}
+
+func (s *Server) push(args []string) reply {
+	key := args[1]
+	for _, value := range args[2:] {
+		if strings.EqualFold(args[0], "LPUSH") {
+			s.lists[key] = append([]string{value}, s.lists[key]...)
+		} else {
+			s.lists[key] = append(s.lists[key], value)
+		}
+	}
+	s.touch(key)
+	return integer(len(s.lists[key]))
+}
+
+func (s *Server) lrange(args []string) reply {
+	list := s.lists[args[1]]
+	start, stop := listRange(len(list), atoi(args[2]), atoi(args[3]))
+	return array(list[start:stop])
+}
+
+func (s *Server) lrem(args []string) reply {
+	key, count, value := args[1], atoi(args[2]), args[3] // отрицательный count не поддерживается
+	list := s.lists[key]
+	kept := list[:0:0]
+	removed := 0
+	for _, item := range list {
+		if item == value && (count == 0 || removed < count) {
+			removed++
+			continue
+		}
+		kept = append(kept, item)
+	}
+	s.setList(key, kept)
+	return integer(removed)
+}
+
+func (s *Server) llen(args []string) reply {
+	return integer(len(s.lists[args[1]]))
+}
+
+func (s *Server) lpos(args []string) reply {
+	for i, item := range s.lists[args[1]] {
+		if item == args[2] {
+			return integer(i)
+		}
+	}
+	return nullBulk()
+}
+
+// blmove — BLMOVE source destination RIGHT LEFT timeout; пустой источник не ожидается
+func (s *Server) blmove(args []string) reply {
+	if !strings.EqualFold(args[3], "RIGHT") || !strings.EqualFold(args[4], "LEFT") {
+		return errorReply("ERR only BLMOVE RIGHT LEFT is supported by redistest")
+	}
+	source, destination := args[1], args[2]
+	list := s.lists[source]
+	if len(list) == 0 {
+		return nullBulk()
+	}
+
+	value := list[len(list)-1]
+	s.setList(source, list[:len(list)-1])
+	s.lists[destination] = append([]string{value}, s.lists[destination]...)
+	s.touch(destination)
+	return bulk(value)
+}
+
+func (s *Server) setList(key string, list []string) {
+	if len(list) == 0 {
+		delete(s.lists, key)
+	} else {
+		s.lists[key] = append([]string(nil), list...)
+	}
+	s.touch(key)
+}
+
+// zadd — ZADD key [NX] score member [score member ...]
+func (s *Server) zadd(args []string) reply {
+	key, rest := args[1], args[2:]
+	nx := false
+	if len(rest) > 0 && strings.EqualFold(rest[0], "NX") {
+		nx, rest = true, rest[1:]
+	}
+	if s.zsets[key] == nil {
+		s.zsets[key] = make(map[string]float64)
+	}
+
+	added := 0
+	for i := 0; i+1 < len(rest); i += 2 {
+		score, err := strconv.ParseFloat(rest[i], 64)
+		if err != nil {
+			return errorReply("ERR value is not a valid float")
+		}
+		member := rest[i+1]
+		if _, exists := s.zsets[key][member]; exists {
+			if nx {
+				continue
+			}
+		} else {
+			added++
+		}
+		s.zsets[key][member] = score
+	}
+	s.touch(key)
+	return integer(added)
+}
+
+func (s *Server) zrem(args []string) reply {
+	key := args[1]
+	removed := 0
+	for _, member := range args[2:] {
+		if _, ok := s.zsets[key][member]; ok {
+			delete(s.zsets[key], member)
+			removed++
+		}
+	}
+	if len(s.zsets[key]) == 0 {
+		delete(s.zsets, key)
+	}
+	s.touch(key)
+	return integer(removed)
+}
+
+func (s *Server) zcard(args []string) reply {
+	return integer(len(s.zsets[args[1]]))
+}
+
+func (s *Server) sortedMembers(key string) []zmember {
+	members := make([]zmember, 0, len(s.zsets[key]))
+	for member, score := range s.zsets[key] {
+		members = append(members, zmember{member: member, score: score})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].score != members[j].score {
+			return members[i].score < members[j].score
+		}
+		return members[i].member < members[j].member
+	})
+	return members
+}
+
+// zrangeByScore — ZRANGEBYSCORE key min max; границы включительные
+func (s *Server) zrangeByScore(args []string) reply {
+	min, errMin := parseScore(args[2])
+	max, errMax := parseScore(args[3])
+	if errMin != nil || errMax != nil {
+		return errorReply("ERR min or max is not a float")
+	}
+
+	var result []string
+	for _, m := range s.sortedMembers(args[1]) {
+		if m.score >= min && m.score <= max {
+			result = append(result, m.member)
+		}
+	}
+	return array(result)
+}
+
+func (s *Server) zrevrange(args []string) reply {
+	members := s.sortedMembers(args[1])
+	for i, j := 0, len(members)-1; i < j; i, j = i+1, j-1 {
+		members[i], members[j] = members[j], members[i]
+	}
+	start, stop := listRange(len(members), atoi(args[2]), atoi(args[3]))
+	result := make([]string, 0, stop-start)
+	for _, m := range members[start:stop] {
+		result = append(result, m.member)
+	}
+	return array(result)
+}
+
+func (s *Server) hset(args []string) reply {
+	key := args[1]
+	if s.hashes[key] == nil {
+		s.hashes[key] = make(map[string]string)
+	}
+	added := 0
+	for i := 2; i+1 < len(args); i += 2 {
+		if _, exists := s.hashes[key][args[i]]; !exists {
+			added++
+		}
+		s.hashes[key][args[i]] = args[i+1]
+	}
+	s.touch(key)
+	return integer(added)
+}
+
+func (s *Server) hmget(args []string) reply {
+	items := make([]reply, 0, len(args)-2)
+	for _, field := range args[2:] {
+		if value, ok := s.hashes[args[1]][field]; ok {
+			items = append(items, bulk(value))
+		} else {
+			items = append(items, nullBulk())
+		}
+	}
+	return reply{kind: '*', items: items}
+}
+
+func (s *Server) hgetall(args []string) reply {
+	fields := make([]string, 0, len(s.hashes[args[1]]))
+	for field := range s.hashes[args[1]] {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	values := make([]string, 0, len(fields)*2)
+	for _, field := range fields {
+		values = append(values, field, s.hashes[args[1]][field])
+	}
+	return array(values)
+}
+
+// expire сообщает об успехе для существующего ключа, но ключ не удаляется
+func (s *Server) expire(args []string) reply {
+	if s.exists(args[1]) {
+		return integer(1)
+	}
+	return integer(0)
+}
+
+type streamID struct {
+	ms, seq uint64
+}
+
+func (id streamID) String() string {
+	return fmt.Sprintf("%d-%d", id.ms, id.seq)
+}
+
+func (id streamID) less(other streamID) bool {
+	if id.ms != other.ms {
+		return id.ms < other.ms
+	}
+	return id.seq < other.seq
+}
+
+type streamEntry struct {
+	id     streamID
+	fields []string
+}
+
+// xadd — XADD key MAXLEN ~ n * field value [field value ...]; поток обрезается точно до n записей
+func (s *Server) xadd(args []string) reply {
+	if len(args) < 8 || !strings.EqualFold(args[2], "MAXLEN") || args[3] != "~" || args[5] != "*" {
+		return errorReply("ERR only XADD key MAXLEN ~ n * ... is supported by redistest")
+	}
+	key, maxLen := args[1], atoi(args[4])
+
+	entries := s.streams[key]
+	id := streamID{ms: uint64(time.Now().UnixMilli())}
+	if len(entries) > 0 {
+		if last := entries[len(entries)-1].id; !last.less(id) {
+			id = streamID{ms: last.ms, seq: last.seq + 1}
+		}
+	}
+
+	entries = append(entries, streamEntry{id: id, fields: append([]string(nil), args[6:]...)})
+	if len(entries) > maxLen {
+		entries = entries[len(entries)-maxLen:]
+	}
+	s.streams[key] = entries
+	s.touch(key)
+	return bulk(id.String())
+}
+
+// xrange — XRANGE key start end [COUNT n] и XREVRANGE key end start [COUNT n];
+// границы "-", "+" и исключающие "(id"
+func (s *Server) xrange(args []string) reply {
+	reverse := strings.EqualFold(args[0], "XREVRANGE")
+	startArg, endArg := args[2], args[3]
+	if reverse {
+		startArg, endArg = endArg, startArg
+	}
+	count := -1
+	if len(args) >= 6 && strings.EqualFold(args[4], "COUNT") {
+		count = atoi(args[5])
+	}
+
+	start, startExclusive, ok := parseStreamBound(startArg)
+	if !ok {
+		return errorReply("ERR Invalid stream ID specified as stream command argument")
+	}
+	end, endExclusive, ok := parseStreamBound(endArg)
+	if !ok {
+		return errorReply("ERR Invalid stream ID specified as stream command argument")
+	}
+
+	var matched []streamEntry
+	for _, entry := range s.streams[args[1]] {
+		if entry.id.less(start) || (startExclusive && entry.id == start) {
+			continue
+		}
+		if end.less(entry.id) || (endExclusive && entry.id == end) {
+			continue
+		}
+		matched = append(matched, entry)
+	}
+	if reverse {
+		slices.Reverse(matched)
+	}
+	if count >= 0 && len(matched) > count {
+		matched = matched[:count]
+	}
+
+	items := make([]reply, 0, len(matched))
+	for _, entry := range matched {
+		items = append(items, reply{kind: '*', items: []reply{bulk(entry.id.String()), array(entry.fields)}})
+	}
+	return reply{kind: '*', items: items}
+}
+
+// parseStreamBound разбирает границу XRANGE: "-", "+", <ms>-<seq> или исключающую (<ms>-<seq>
+func parseStreamBound(value string) (id streamID, exclusive, ok bool) {
+	switch value {
+	case "-":
+		return streamID{}, false, true
+	case "+":
+		return streamID{ms: math.MaxUint64, seq: math.MaxUint64}, false, true
+	}
+	if strings.HasPrefix(value, "(") {
+		exclusive, value = true, value[1:]
+	}
+	msPart, seqPart, found := strings.Cut(value, "-")
+	ms, errMs := strconv.ParseUint(msPart, 10, 64)
+	seq, errSeq := strconv.ParseUint(seqPart, 10, 64)
+	if !found || errMs != nil || errSeq != nil {
+		return streamID{}, false, false
+	}
+	return streamID{ms: ms, seq: seq}, exclusive, true
+}
+
+func parseScore(value string) (float64, error) {
+	switch strings.ToLower(value) {
+	case "-inf":
+		return math.Inf(-1), nil
+	case "+inf", "inf":
+		return math.Inf(1), nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// listRange переводит индексы LRANGE (включительные, отрицательные — с конца) в границы среза
+func listRange(length, start, stop int) (int, int) {
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	start = max(start, 0)
+	stop = min(stop+1, length)
+	if start >= stop {
+		return 0, 0
+	}
+	return start, stop
+}
+
+func atoi(value string) int {
+	n, _ := strconv.Atoi(value)
+	return n
+}
+
+// reply — ответ в протоколе RESP2
+type reply struct {
+	kind  byte // '+', '-', ':', '$', '*'
+	text  string
+	num   int
+	null  bool
+	items []reply
+}
+
+func simple(text string) reply          { return reply{kind: '+', text: text} }
+func errorReply(text string) reply      { return reply{kind: '-', text: text} }
+func integer(n int) reply               { return reply{kind: ':', num: n} }
+func bulk(text string) reply            { return reply{kind: '$', text: text} }
+func nullBulk() reply                   { return reply{kind: '$', null: true} }
+func writeSimple(w io.Writer, t string) { simple(t).write(w) }
+
+func array(values []string) reply {
+	items := make([]reply, 0, len(values))
+	for _, value := range values {
+		items = append(items, bulk(value))
+	}
+	return reply{kind: '*', items: items}
+}
+
+func (r reply) write(w io.Writer) {
+	switch r.kind {
+	case '+', '-':
+		fmt.Fprintf(w, "%c%s\r\n", r.kind, r.text)
+	case ':':
+		fmt.Fprintf(w, ":%d\r\n", r.num)
+	case '$':
+		if r.null {
+			io.WriteString(w, "$-1\r\n")
+			return
+		}
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(r.text), r.text)
+	case '*':
+		fmt.Fprintf(w, "*%d\r\n", len(r.items))
+		for _, item := range r.items {
+			item.write(w)
+		}
+	}
+}
+
+// readCommand читает команду клиента — массив bulk-строк
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		return nil, errors.New("redistest: inline commands are not supported")
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("redistest: invalid array header %q", line)
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		header, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(header) == 0 || header[0] != '$' {
+			return nil, fmt.Errorf("redistest: invalid bulk header %q", header)
+		}
+		size, err := strconv.Atoi(header[1:])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("redistest: invalid bulk size %q", header)
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"geo_system_core/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type APIKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(db *pgxpool.Pool) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, tenant_id, name, prefix, key_hash, scopes, expires_at, revoked_at, last_used_at, created_at`
+
+func scanAPIKey(row pgx.Row) (*models.APIKey, error) {
+	var key models.APIKey
+	err := row.Scan(
+		&key.ID, &key.TenantID, &key.Name, &key.Prefix, &key.KeyHash, &key.Scopes,
+		&key.ExpiresAt, &key.RevokedAt, &key.LastUsedAt, &key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *APIKeyRepository) Create(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	query := `
+		INSERT INTO api_keys (id, tenant_id, name, prefix, key_hash, scopes, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + apiKeyColumns
+
+	created, err := scanAPIKey(r.db.QueryRow(ctx, query,
+		uuid.New(), key.TenantID, key.Name, key.Prefix, key.KeyHash, key.Scopes, key.ExpiresAt, time.Now(),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r *APIKeyRepository) GetByID(ctx context.Context, tenantID string, id uuid.UUID) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = $1 AND tenant_id = $2`
+
+	key, err := scanAPIKey(r.db.QueryRow(ctx, query, id, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("api key not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return key, nil
+}
+
+func (r *APIKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE prefix = $1`
+
+	key, err := scanAPIKey(r.db.QueryRow(ctx, query, prefix))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("api key not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return key, nil
+}
+
+func (r *APIKeyRepository) List(ctx context.Context, tenantID string) ([]models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE tenant_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Revoke отзывает ключ; повторный отзыв не меняет время отзыва
+func (r *APIKeyRepository) Revoke(ctx context.Context, tenantID string, id uuid.UUID) (*models.APIKey, error) {
+	query := `
+		UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING ` + apiKeyColumns
+
+	key, err := scanAPIKey(r.db.QueryRow(ctx, query, id, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("api key not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// Rotate в одной транзакции создает новый ключ и сокращает срок действия старого до graceUntil.
+// Оба ключа принадлежат арендатору key.TenantID.
+func (r *APIKeyRepository) Rotate(ctx context.Context, oldID uuid.UUID, key *models.APIKey, graceUntil time.Time) (*models.APIKey, error) {
+	var created *models.APIKey
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE api_keys SET expires_at = LEAST(COALESCE(expires_at, $2), $2)
+			WHERE id = $1 AND tenant_id = $3 AND revoked_at IS NULL
+		`, oldID, graceUntil, key.TenantID)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return fmt.Errorf("api key not found")
+		}
+
+		query := `
+			INSERT INTO api_keys (id, tenant_id, name, prefix, key_hash, scopes, expires_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING ` + apiKeyColumns
+		created, err = scanAPIKey(tx.QueryRow(ctx, query,
+			uuid.New(), key.TenantID, key.Name, key.Prefix, key.KeyHash, key.Scopes, key.ExpiresAt, time.Now(),
+		))
+		return err
+	})
+	if err != nil {
+		if err.Error() == "api key not found" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to rotate api key: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	_, err := r.db.Exec(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, id, usedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update api key last use: %w", err)
+	}
+
+	return nil
+}
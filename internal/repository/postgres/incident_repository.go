@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"geo_system_core/internal/models"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,43 +21,76 @@ func NewIncidentRepository(db *pgxpool.Pool) *IncidentRepository {
 	return &IncidentRepository{db: db}
 }
 
-func (r *IncidentRepository) Create(ctx context.Context, req models.CreateIncidentRequest) (*models.Incident, error) {
-	incident := &models.Incident{
-		ID:          uuid.New(),
-		Title:       req.Title,
-		Description: req.Description,
-		Latitude:    req.Latitude,
-		Longitude:   req.Longitude,
-		Radius:      req.Radius,
-		Severity:    req.Severity,
-		Status:      req.Status,
-		IsActive:    true,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+const incidentColumns = `id, tenant_id, title, description, latitude, longitude, radius, geometry, severity, status, is_active, version, starts_at, expires_at, created_at, updated_at`
+
+// activeWindowCondition отсекает инциденты, чье окно действия еще не началось или уже закончилось
+const activeWindowCondition = `(starts_at IS NULL OR starts_at <= NOW()) AND (expires_at IS NULL OR expires_at > NOW())`
+
+func scanIncident(row pgx.Row) (*models.Incident, error) {
+	var incident models.Incident
+	err := row.Scan(
+		&incident.ID, &incident.TenantID, &incident.Title, &incident.Description,
+		&incident.Latitude, &incident.Longitude, &incident.Radius, &incident.Geometry,
+		&incident.Severity, &incident.Status, &incident.IsActive, &incident.Version,
+		&incident.StartsAt, &incident.ExpiresAt,
+		&incident.CreatedAt, &incident.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
 	}
+	return &incident, nil
+}
 
-	if incident.Status == "" {
-		incident.Status = "active"
+func scanIncidents(rows pgx.Rows) ([]models.Incident, error) {
+	defer rows.Close()
+
+	var incidents []models.Incident
+	for rows.Next() {
+		incident, err := scanIncident(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan incident: %w", err)
+		}
+		incidents = append(incidents, *incident)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read incidents: %w", err)
 	}
 
-	query := `
-		INSERT INTO incidents (id, title, description, latitude, longitude, radius, severity, status, is_active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		RETURNING id, title, description, latitude, longitude, radius, severity, status, is_active, created_at, updated_at
-	`
+	return incidents, nil
+}
 
-	err := r.db.QueryRow(ctx, query,
-		incident.ID, incident.Title, incident.Description,
-		incident.Latitude, incident.Longitude, incident.Radius,
-		incident.Severity, incident.Status, incident.IsActive,
-		incident.CreatedAt, incident.UpdatedAt,
-	).Scan(
-		&incident.ID, &incident.Title, &incident.Description,
-		&incident.Latitude, &incident.Longitude, &incident.Radius,
-		&incident.Severity, &incident.Status, &incident.IsActive,
-		&incident.CreatedAt, &incident.UpdatedAt,
-	)
+// Create создает инцидент и первую запись его истории в одной транзакции
+func (r *IncidentRepository) Create(ctx context.Context, tenantID string, req models.CreateIncidentRequest, actor models.Actor) (*models.Incident, error) {
+	now := time.Now()
+	id := req.ID
+	if id == uuid.Nil {
+		id = uuid.New()
+	}
+	status := req.Status
+	if status == "" {
+		status = "active"
+	}
 
+	query := `
+		INSERT INTO incidents (id, tenant_id, title, description, latitude, longitude, radius, geometry, severity, status, is_active, starts_at, expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		RETURNING ` + incidentColumns
+
+	var incident *models.Incident
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		var err error
+		incident, err = scanIncident(tx.QueryRow(ctx, query,
+			id, tenantID, req.Title, req.Description,
+			req.Latitude, req.Longitude, req.Radius, req.Geometry,
+			req.Severity, status, true,
+			req.StartsAt, req.ExpiresAt,
+			now, now,
+		))
+		if err != nil {
+			return err
+		}
+		return insertHistory(ctx, tx, models.HistoryCreated, nil, incident, actor)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create incident: %w", err)
 	}
@@ -64,22 +98,14 @@ func (r *IncidentRepository) Create(ctx context.Context, req models.CreateIncide
 	return incident, nil
 }
 
-func (r *IncidentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Incident, error) {
-	var incident models.Incident
-
+func (r *IncidentRepository) GetByID(ctx context.Context, tenantID string, id uuid.UUID) (*models.Incident, error) {
 	query := `
-		SELECT id, title, description, latitude, longitude, radius, severity, status, is_active, created_at, updated_at
+		SELECT ` + incidentColumns + `
 		FROM incidents
-		WHERE id = $1 AND is_active = true
+		WHERE id = $1 AND tenant_id = $2 AND is_active = true
 	`
 
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&incident.ID, &incident.Title, &incident.Description,
-		&incident.Latitude, &incident.Longitude, &incident.Radius,
-		&incident.Severity, &incident.Status, &incident.IsActive,
-		&incident.CreatedAt, &incident.UpdatedAt,
-	)
-
+	incident, err := scanIncident(r.db.QueryRow(ctx, query, id, tenantID))
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, fmt.Errorf("incident not found")
 	}
@@ -87,196 +113,482 @@ func (r *IncidentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models
 		return nil, fmt.Errorf("failed to get incident: %w", err)
 	}
 
-	return &incident, nil
+	return incident, nil
 }
 
-func (r *IncidentRepository) List(ctx context.Context, page, limit int) ([]models.Incident, int, error) {
-	offset := (page - 1) * limit
+// Owner возвращает арендатора инцидента с этим ID и признак его активности, в том числе для удаленных
+// инцидентов и инцидентов других арендаторов
+func (r *IncidentRepository) Owner(ctx context.Context, id uuid.UUID) (tenantID string, isActive bool, err error) {
+	query := `SELECT tenant_id, is_active FROM incidents WHERE id = $1`
+
+	err = r.db.QueryRow(ctx, query, id).Scan(&tenantID, &isActive)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", false, fmt.Errorf("incident not found")
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get incident owner: %w", err)
+	}
+
+	return tenantID, isActive, nil
+}
+
+// List возвращает страницу активных инцидентов арендатора по фильтру; фильтр уже проверен сервисом.
+// При заданном after выбираются записи после курсора в порядке (created_at, id), а offset не используется.
+// Запрос количества использует условия фильтра без курсора, так что total — размер всего списка.
+func (r *IncidentRepository) List(
+	ctx context.Context,
+	tenantID string,
+	filter models.IncidentFilter,
+	after *models.Cursor,
+	offset, limit int,
+) ([]models.Incident, int, error) {
+	args := []any{tenantID}
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := []string{"tenant_id = $1", "is_active = true"}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = "+arg(filter.Status))
+	}
+	if len(filter.Severity) > 0 {
+		conditions = append(conditions, "severity = ANY("+arg(filter.Severity)+")")
+	}
+	if filter.CreatedFrom != nil {
+		conditions = append(conditions, "created_at >= "+arg(*filter.CreatedFrom))
+	}
+	if filter.CreatedTo != nil {
+		conditions = append(conditions, "created_at <= "+arg(*filter.CreatedTo))
+	}
+	if filter.UpdatedFrom != nil {
+		conditions = append(conditions, "updated_at >= "+arg(*filter.UpdatedFrom))
+	}
+	if filter.UpdatedTo != nil {
+		conditions = append(conditions, "updated_at <= "+arg(*filter.UpdatedTo))
+	}
+	if filter.MinLat != nil {
+		conditions = append(conditions,
+			"latitude BETWEEN "+arg(*filter.MinLat)+" AND "+arg(*filter.MaxLat),
+			"longitude BETWEEN "+arg(*filter.MinLng)+" AND "+arg(*filter.MaxLng))
+	}
+
+	// Расстояние от точки до центра зоны по формуле гаверсинуса; LEAST защищает acos
+	// от значений чуть больше 1 из-за погрешности округления
+	distance := ""
+	if filter.Lat != nil {
+		lat, lng := arg(*filter.Lat), arg(*filter.Lng)
+		distance = `6371000 * acos(LEAST(1.0,
+			cos(radians(` + lat + `)) * cos(radians(latitude)) *
+			cos(radians(longitude) - radians(` + lng + `)) +
+			sin(radians(` + lat + `)) * sin(radians(latitude))))`
+		if filter.Radius != nil {
+			conditions = append(conditions, distance+" - radius <= "+arg(*filter.Radius))
+		}
+	}
+	if filter.Query != "" {
+		conditions = append(conditions, incidentSearchVector+" @@ websearch_to_tsquery('simple', "+arg(filter.Query)+")")
+	}
+	where := strings.Join(conditions, " AND ")
 
 	// Получаем общее количество
 	var total int
-	countQuery := `SELECT COUNT(*) FROM incidents WHERE is_active = true`
-	err := r.db.QueryRow(ctx, countQuery).Scan(&total)
+	countQuery := `SELECT COUNT(*) FROM incidents WHERE ` + where
+	err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count incidents: %w", err)
 	}
 
+	if after != nil {
+		// Сравнение кортежей совпадает с порядком created_at, id и использует индекс
+		operator := "<"
+		if filter.Sort == "created_at" {
+			operator = ">"
+		}
+		where += " AND (created_at, id) " + operator + " (" + arg(after.CreatedAt) + ", " + arg(after.ID) + ")"
+		offset = 0
+	}
+
 	// Получаем список
 	query := `
-		SELECT id, title, description, latitude, longitude, radius, severity, status, is_active, created_at, updated_at
+		SELECT ` + incidentColumns + `
 		FROM incidents
-		WHERE is_active = true
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`
+		WHERE ` + where + `
+		ORDER BY ` + incidentOrder(filter.Sort, distance) + `
+		LIMIT ` + arg(limit) + ` OFFSET ` + arg(offset)
 
-	rows, err := r.db.Query(ctx, query, limit, offset)
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to list incidents: %w", err)
 	}
-	defer rows.Close()
 
-	var incidents []models.Incident
-	for rows.Next() {
-		var incident models.Incident
-		err := rows.Scan(
-			&incident.ID, &incident.Title, &incident.Description,
-			&incident.Latitude, &incident.Longitude, &incident.Radius,
-			&incident.Severity, &incident.Status, &incident.IsActive,
-			&incident.CreatedAt, &incident.UpdatedAt,
-		)
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to scan incident: %w", err)
-		}
-		incidents = append(incidents, incident)
+	incidents, err := scanIncidents(rows)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	return incidents, total, nil
 }
 
-func (r *IncidentRepository) Update(ctx context.Context, id uuid.UUID, req models.UpdateIncidentRequest) (*models.Incident, error) {
-	// Сначала получаем текущий инцидент
-	incident, err := r.GetByID(ctx, id)
-	if err != nil {
-		return nil, err
+// incidentSearchVector — выражение полнотекстового поиска; совпадает с выражением индекса
+// idx_incidents_search, иначе индекс не используется
+const incidentSearchVector = `to_tsvector('simple', title || ' ' || COALESCE(description, ''))`
+
+// incidentOrder возвращает ORDER BY для параметра sort: поле с необязательным префиксом "-"
+// для обратного порядка. По умолчанию новые инциденты первыми; id делает порядок стабильным между страницами.
+func incidentOrder(sort, distance string) string {
+	direction := "ASC"
+	if strings.HasPrefix(sort, "-") {
+		direction = "DESC"
+		sort = sort[1:]
 	}
 
-	// Обновляем поля
-	if req.Title != nil {
-		incident.Title = *req.Title
+	column := ""
+	switch sort {
+	case "created_at", "updated_at":
+		column = sort
+	case "severity":
+		column = "CASE severity WHEN 'low' THEN 1 WHEN 'medium' THEN 2 WHEN 'high' THEN 3 WHEN 'critical' THEN 4 END"
+	case "distance":
+		column = distance // пустое, если точка не задана
 	}
-	if req.Description != nil {
-		incident.Description = *req.Description
+	if column == "" {
+		column, direction = "created_at", "DESC"
 	}
-	if req.Latitude != nil {
-		incident.Latitude = *req.Latitude
+
+	return column + " " + direction + ", id " + direction
+}
+
+// Update в одной транзакции читает инцидент арендатора с блокировкой строки, передает копию в mutate
+// и сохраняет все изменяемые поля с увеличением версии. Слияние с запросом, валидация и проверка
+// версии выполняются в mutate; его ошибка отменяет обновление и возвращается без изменений.
+// Предыдущее состояние попадает в историю вместе с новым.
+func (r *IncidentRepository) Update(
+	ctx context.Context,
+	tenantID string,
+	id uuid.UUID,
+	actor models.Actor,
+	mutate func(incident *models.Incident) error,
+) (*models.Incident, error) {
+	query := `
+		UPDATE incidents
+		SET title = $1, description = $2, latitude = $3, longitude = $4, radius = $5, geometry = $6,
+		    severity = $7, status = $8, starts_at = $9, expires_at = $10, updated_at = $11, version = version + 1
+		WHERE id = $12
+		RETURNING ` + incidentColumns
+
+	var (
+		updated   *models.Incident
+		mutateErr error
+	)
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		before, err := lockIncident(ctx, tx, tenantID, id)
+		if err != nil {
+			return err
+		}
+		incident := *before
+		if mutateErr = mutate(&incident); mutateErr != nil {
+			return mutateErr
+		}
+
+		updated, err = scanIncident(tx.QueryRow(ctx, query,
+			incident.Title, incident.Description,
+			incident.Latitude, incident.Longitude, incident.Radius, incident.Geometry,
+			incident.Severity, incident.Status, incident.StartsAt, incident.ExpiresAt, time.Now(),
+			id,
+		))
+		if err != nil {
+			return err
+		}
+
+		action := models.HistoryUpdated
+		if before.Status != updated.Status {
+			action = models.HistoryStatusChanged
+		}
+		return insertHistory(ctx, tx, action, before, updated, actor)
+	})
+	if mutateErr != nil {
+		return nil, mutateErr
 	}
-	if req.Longitude != nil {
-		incident.Longitude = *req.Longitude
+	if err != nil {
+		if err.Error() == "incident not found" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update incident: %w", err)
 	}
-	if req.Radius != nil {
-		incident.Radius = *req.Radius
+
+	return updated, nil
+}
+
+// Delete деактивирует инцидент арендатора. check, если задан, получает текущее состояние
+// под блокировкой строки; его ошибка отменяет удаление и возвращается без изменений.
+// Возвращает деактивированный инцидент.
+func (r *IncidentRepository) Delete(
+	ctx context.Context,
+	tenantID string,
+	id uuid.UUID,
+	actor models.Actor,
+	check func(incident *models.Incident) error,
+) (*models.Incident, error) {
+	query := `UPDATE incidents SET is_active = false, updated_at = $1, version = version + 1 WHERE id = $2 RETURNING ` + incidentColumns
+
+	var (
+		deleted  *models.Incident
+		checkErr error
+	)
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		before, err := lockIncident(ctx, tx, tenantID, id)
+		if err != nil {
+			return err
+		}
+		if check != nil {
+			if checkErr = check(before); checkErr != nil {
+				return checkErr
+			}
+		}
+
+		deleted, err = scanIncident(tx.QueryRow(ctx, query, time.Now(), id))
+		if err != nil {
+			return err
+		}
+		return insertHistory(ctx, tx, models.HistoryDeleted, before, deleted, actor)
+	})
+	if checkErr != nil {
+		return nil, checkErr
 	}
-	if req.Severity != nil {
-		incident.Severity = *req.Severity
+	if err != nil {
+		if err.Error() == "incident not found" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to delete incident: %w", err)
 	}
-	if req.Status != nil {
-		incident.Status = *req.Status
+
+	return deleted, nil
+}
+
+// History возвращает версии инцидента арендатора начиная с последней, включая удаленные инциденты
+func (r *IncidentRepository) History(ctx context.Context, tenantID string, id uuid.UUID, offset, limit int) ([]models.IncidentHistoryEntry, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM incident_history WHERE incident_id = $1 AND tenant_id = $2`
+	if err := r.db.QueryRow(ctx, countQuery, id, tenantID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count incident history: %w", err)
 	}
-	incident.UpdatedAt = time.Now()
 
 	query := `
-		UPDATE incidents
-		SET title = $1, description = $2, latitude = $3, longitude = $4, radius = $5, severity = $6, status = $7, updated_at = $8
-		WHERE id = $9 AND is_active = true
-		RETURNING id, title, description, latitude, longitude, radius, severity, status, is_active, created_at, updated_at
+		SELECT incident_id, version, action, before, after, actor_key_id, actor, changed_at
+		FROM incident_history
+		WHERE incident_id = $1 AND tenant_id = $2
+		ORDER BY version DESC
+		LIMIT $3 OFFSET $4
 	`
 
-	err = r.db.QueryRow(ctx, query,
-		incident.Title, incident.Description,
-		incident.Latitude, incident.Longitude, incident.Radius,
-		incident.Severity, incident.Status, incident.UpdatedAt,
-		id,
-	).Scan(
-		&incident.ID, &incident.Title, &incident.Description,
-		&incident.Latitude, &incident.Longitude, &incident.Radius,
-		&incident.Severity, &incident.Status, &incident.IsActive,
-		&incident.CreatedAt, &incident.UpdatedAt,
-	)
+	rows, err := r.db.Query(ctx, query, id, tenantID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get incident history: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.IncidentHistoryEntry{}
+	for rows.Next() {
+		var entry models.IncidentHistoryEntry
+		err := rows.Scan(
+			&entry.IncidentID, &entry.Version, &entry.Action, &entry.Before, &entry.After,
+			&entry.ActorKeyID, &entry.Actor, &entry.ChangedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan incident history: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read incident history: %w", err)
+	}
+
+	return entries, total, nil
+}
+
+// GetAsOf восстанавливает инцидент по последней версии, записанной не позже asOf.
+// Инцидент, который в этот момент еще не существовал или был удален, не найден.
+func (r *IncidentRepository) GetAsOf(ctx context.Context, tenantID string, id uuid.UUID, asOf time.Time) (*models.Incident, error) {
+	query := `
+		SELECT after
+		FROM incident_history
+		WHERE incident_id = $1 AND tenant_id = $2 AND changed_at <= $3
+		ORDER BY version DESC
+		LIMIT 1
+	`
 
+	var incident *models.Incident
+	err := r.db.QueryRow(ctx, query, id, tenantID, asOf).Scan(&incident)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && (incident == nil || !incident.IsActive)) {
+		return nil, fmt.Errorf("incident not found")
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to update incident: %w", err)
+		return nil, fmt.Errorf("failed to get incident history: %w", err)
 	}
 
 	return incident, nil
 }
 
-func (r *IncidentRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `UPDATE incidents SET is_active = false, updated_at = $1 WHERE id = $2 AND is_active = true`
+// lockIncident читает активный инцидент арендатора с блокировкой строки до конца транзакции
+func lockIncident(ctx context.Context, tx pgx.Tx, tenantID string, id uuid.UUID) (*models.Incident, error) {
+	query := `
+		SELECT ` + incidentColumns + `
+		FROM incidents
+		WHERE id = $1 AND tenant_id = $2 AND is_active = true
+		FOR UPDATE
+	`
 
-	result, err := r.db.Exec(ctx, query, time.Now(), id)
-	if err != nil {
-		return fmt.Errorf("failed to delete incident: %w", err)
+	incident, err := scanIncident(tx.QueryRow(ctx, query, id, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("incident not found")
 	}
+	return incident, err
+}
 
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("incident not found")
+// insertHistory записывает новую версию инцидента after; номер записи совпадает с его версией
+func insertHistory(ctx context.Context, tx pgx.Tx, action string, before, after *models.Incident, actor models.Actor) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO incident_history (incident_id, tenant_id, version, action, before, after, actor_key_id, actor, changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+	`, after.ID, after.TenantID, after.Version, action, before, after, actor.KeyID, actor.Name)
+	if err != nil {
+		return fmt.Errorf("failed to record incident history: %w", err)
 	}
 
 	return nil
 }
 
-func (r *IncidentRepository) FindNearby(ctx context.Context, lat, lng, maxDistance float64) ([]models.Incident, error) {
+// FindNearby возвращает активные инциденты арендатора, граница описанной окружности которых
+// находится не дальше maxDistance метров от точки
+func (r *IncidentRepository) FindNearby(ctx context.Context, tenantID string, lat, lng, maxDistance float64) ([]models.Incident, error) {
 	// Используем формулу гаверсинуса для расчета расстояния
 	// Используем подзапрос для фильтрации по расстоянию
 	query := `
-		SELECT id, title, description, latitude, longitude, radius, severity, status, is_active, created_at, updated_at
+		SELECT ` + incidentColumns + `
 		FROM (
-			SELECT id, title, description, latitude, longitude, radius, severity, status, is_active, created_at, updated_at,
+			SELECT ` + incidentColumns + `,
 			       6371000 * acos(
 			           cos(radians($1)) * cos(radians(latitude)) *
 			           cos(radians(longitude) - radians($2)) +
 			           sin(radians($1)) * sin(radians(latitude))
 			       ) AS distance
 			FROM incidents
-			WHERE is_active = true AND status = 'active'
+			WHERE tenant_id = $4 AND is_active = true AND status = 'active' AND ` + activeWindowCondition + `
 		) AS incidents_with_distance
-		WHERE distance <= $3
+		WHERE distance - radius <= $3
 		ORDER BY distance
 	`
 
-	rows, err := r.db.Query(ctx, query, lat, lng, maxDistance)
+	rows, err := r.db.Query(ctx, query, lat, lng, maxDistance, tenantID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find nearby incidents: %w", err)
 	}
-	defer rows.Close()
 
-	var incidents []models.Incident
-	for rows.Next() {
-		var incident models.Incident
-		err := rows.Scan(
-			&incident.ID, &incident.Title, &incident.Description,
-			&incident.Latitude, &incident.Longitude, &incident.Radius,
-			&incident.Severity, &incident.Status, &incident.IsActive,
-			&incident.CreatedAt, &incident.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan incident: %w", err)
-		}
-		incidents = append(incidents, incident)
+	return scanIncidents(rows)
+}
+
+func (r *IncidentRepository) GetActiveIncidents(ctx context.Context, tenantID string) ([]models.Incident, error) {
+	query := `
+		SELECT ` + incidentColumns + `
+		FROM incidents
+		WHERE tenant_id = $1 AND is_active = true AND status = 'active' AND ` + activeWindowCondition + `
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active incidents: %w", err)
 	}
 
-	return incidents, nil
+	return scanIncidents(rows)
 }
 
-func (r *IncidentRepository) GetActiveIncidents(ctx context.Context) ([]models.Incident, error) {
+// GetActiveIncidentsAt возвращает инциденты арендатора, действующие в момент at, в том числе
+// запланированные на будущее
+func (r *IncidentRepository) GetActiveIncidentsAt(ctx context.Context, tenantID string, at time.Time) ([]models.Incident, error) {
 	query := `
-		SELECT id, title, description, latitude, longitude, radius, severity, status, is_active, created_at, updated_at
+		SELECT ` + incidentColumns + `
 		FROM incidents
-		WHERE is_active = true AND status = 'active'
-		ORDER BY created_at DESC
+		WHERE tenant_id = $1 AND is_active = true AND status = 'active'
+		  AND (starts_at IS NULL OR starts_at <= $2) AND (expires_at IS NULL OR expires_at > $2)
 	`
 
-	rows, err := r.db.Query(ctx, query)
+	rows, err := r.db.Query(ctx, query, tenantID, at)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get active incidents: %w", err)
 	}
-	defer rows.Close()
 
-	var incidents []models.Incident
-	for rows.Next() {
-		var incident models.Incident
-		err := rows.Scan(
-			&incident.ID, &incident.Title, &incident.Description,
-			&incident.Latitude, &incident.Longitude, &incident.Radius,
-			&incident.Severity, &incident.Status, &incident.IsActive,
-			&incident.CreatedAt, &incident.UpdatedAt,
-		)
+	return scanIncidents(rows)
+}
+
+// GetUnexpiredIncidents возвращает активные инциденты, срок действия которых еще не истек,
+// включая запланированные на будущее, всех арендаторов. Используется для построения индекса в памяти.
+func (r *IncidentRepository) GetUnexpiredIncidents(ctx context.Context) ([]models.Incident, error) {
+	query := `
+		SELECT ` + incidentColumns + `
+		FROM incidents
+		WHERE is_active = true AND status = 'active' AND (expires_at IS NULL OR expires_at > NOW())
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unexpired incidents: %w", err)
+	}
+
+	return scanIncidents(rows)
+}
+
+// ResolveExpired переводит в статус resolved активные инциденты с истекшим сроком действия
+// всех арендаторов и возвращает их. Строки блокируются с SKIP LOCKED, поэтому при нескольких
+// репликах каждый инцидент будет возвращен только одной из них.
+func (r *IncidentRepository) ResolveExpired(ctx context.Context, actor models.Actor) ([]models.Incident, error) {
+	var resolved []models.Incident
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT `+incidentColumns+`
+			FROM incidents
+			WHERE is_active = true AND status = 'active' AND expires_at <= NOW()
+			FOR UPDATE SKIP LOCKED
+		`)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan incident: %w", err)
+			return err
+		}
+		expired, err := scanIncidents(rows)
+		if err != nil || len(expired) == 0 {
+			return err
 		}
-		incidents = append(incidents, incident)
+
+		ids := make([]uuid.UUID, len(expired))
+		before := make(map[uuid.UUID]*models.Incident, len(expired))
+		for i := range expired {
+			ids[i] = expired[i].ID
+			before[expired[i].ID] = &expired[i]
+		}
+
+		rows, err = tx.Query(ctx, `
+			UPDATE incidents
+			SET status = 'resolved', updated_at = NOW(), version = version + 1
+			WHERE id = ANY($1)
+			RETURNING `+incidentColumns, ids)
+		if err != nil {
+			return err
+		}
+		resolved, err = scanIncidents(rows)
+		if err != nil {
+			return err
+		}
+
+		for i := range resolved {
+			if err := insertHistory(ctx, tx, models.HistoryStatusChanged, before[resolved[i].ID], &resolved[i], actor); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve expired incidents: %w", err)
 	}
 
-	return incidents, nil
+	return resolved, nil
 }
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"geo_system_core/internal/models"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -18,13 +20,13 @@ func NewLocationRepository(db *pgxpool.Pool) *LocationRepository {
 	return &LocationRepository{db: db}
 }
 
-func (r *LocationRepository) SaveCheck(ctx context.Context, userID string, lat, lng float64, hasDanger bool) error {
+func (r *LocationRepository) SaveCheck(ctx context.Context, tenantID, userID string, lat, lng float64, hasDanger bool) error {
 	query := `
-		INSERT INTO location_checks (id, user_id, latitude, longitude, has_danger, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO location_checks (id, tenant_id, user_id, latitude, longitude, has_danger, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`
 
-	_, err := r.db.Exec(ctx, query, uuid.New(), userID, lat, lng, hasDanger, time.Now())
+	_, err := r.db.Exec(ctx, query, uuid.New(), tenantID, userID, lat, lng, hasDanger, time.Now())
 	if err != nil {
 		return fmt.Errorf("failed to save location check: %w", err)
 	}
@@ -32,7 +34,27 @@ func (r *LocationRepository) SaveCheck(ctx context.Context, userID string, lat,
 	return nil
 }
 
-func (r *LocationRepository) GetZoneStats(ctx context.Context, timeWindowMinutes int) ([]models.ZoneStats, error) {
+// SaveChecks сохраняет пакет проверок одним COPY
+func (r *LocationRepository) SaveChecks(ctx context.Context, checks []models.LocationCheckLog) error {
+	rows := make([][]interface{}, len(checks))
+	for i, check := range checks {
+		rows[i] = []interface{}{uuid.New(), check.TenantID, check.UserID, check.Latitude, check.Longitude, check.HasDanger, check.CreatedAt}
+	}
+
+	_, err := r.db.CopyFrom(ctx,
+		pgx.Identifier{"location_checks"},
+		[]string{"id", "tenant_id", "user_id", "latitude", "longitude", "has_danger", "created_at"},
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save location checks: %w", err)
+	}
+
+	return nil
+}
+
+// GetZoneStats считает пользователей в зонах инцидентов арендатора; учитываются только проверки того же арендатора
+func (r *LocationRepository) GetZoneStats(ctx context.Context, tenantID string, timeWindowMinutes int) ([]models.ZoneStats, error) {
 	// Используем параметризованный запрос для безопасности
 	query := `
 		SELECT 
@@ -40,14 +62,15 @@ func (r *LocationRepository) GetZoneStats(ctx context.Context, timeWindowMinutes
 			i.title,
 			COUNT(DISTINCT lc.user_id) as user_count
 		FROM incidents i
-		INNER JOIN location_checks lc ON 
+		INNER JOIN location_checks lc ON lc.tenant_id = i.tenant_id AND
 			6371000 * acos(
 				cos(radians(i.latitude)) * cos(radians(lc.latitude)) *
 				cos(radians(lc.longitude) - radians(i.longitude)) +
 				sin(radians(i.latitude)) * sin(radians(lc.latitude))
 			) <= i.radius
 		WHERE 
-			i.is_active = true 
+			i.tenant_id = $2
+			AND i.is_active = true
 			AND i.status = 'active'
 			AND lc.has_danger = true
 			AND lc.created_at >= NOW() - (INTERVAL '1 minute' * $1)
@@ -55,7 +78,7 @@ func (r *LocationRepository) GetZoneStats(ctx context.Context, timeWindowMinutes
 		ORDER BY user_count DESC
 	`
 
-	rows, err := r.db.Query(ctx, query, timeWindowMinutes)
+	rows, err := r.db.Query(ctx, query, timeWindowMinutes, tenantID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get zone stats: %w", err)
 	}
@@ -73,3 +96,63 @@ func (r *LocationRepository) GetZoneStats(ctx context.Context, timeWindowMinutes
 
 	return stats, nil
 }
+
+// ListChecks возвращает журнал проверок арендатора от новых к старым, начиная после курсора after
+func (r *LocationRepository) ListChecks(
+	ctx context.Context,
+	tenantID string,
+	filter models.LocationCheckFilter,
+	after *models.Cursor,
+	limit int,
+) ([]models.LocationCheckLog, error) {
+	args := []any{tenantID}
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := []string{"tenant_id = $1"}
+	if filter.UserID != "" {
+		conditions = append(conditions, "user_id = "+arg(filter.UserID))
+	}
+	if filter.HasDanger != nil {
+		conditions = append(conditions, "has_danger = "+arg(*filter.HasDanger))
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= "+arg(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at <= "+arg(*filter.To))
+	}
+	if after != nil {
+		conditions = append(conditions, "(created_at, id) < ("+arg(after.CreatedAt)+", "+arg(after.ID)+")")
+	}
+
+	query := `
+		SELECT id, tenant_id, user_id, latitude, longitude, has_danger, created_at
+		FROM location_checks
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT ` + arg(limit)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list location checks: %w", err)
+	}
+	defer rows.Close()
+
+	checks := []models.LocationCheckLog{}
+	for rows.Next() {
+		var check models.LocationCheckLog
+		err := rows.Scan(&check.ID, &check.TenantID, &check.UserID, &check.Latitude, &check.Longitude, &check.HasDanger, &check.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan location check: %w", err)
+		}
+		checks = append(checks, check)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list location checks: %w", err)
+	}
+
+	return checks, nil
+}
@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"geo_system_core/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SubscriptionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSubscriptionRepository(db *pgxpool.Pool) *SubscriptionRepository {
+	return &SubscriptionRepository{db: db}
+}
+
+const subscriptionColumns = `id, tenant_id, url, secret, min_severity, bbox, geometry, event_types, is_active, created_at, updated_at`
+
+func scanSubscription(row pgx.Row) (*models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	err := row.Scan(
+		&sub.ID, &sub.TenantID, &sub.URL, &sub.Secret, &sub.MinSeverity,
+		&sub.BBox, &sub.Geometry, &sub.EventTypes, &sub.IsActive,
+		&sub.CreatedAt, &sub.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func scanSubscriptions(rows pgx.Rows) ([]models.WebhookSubscription, error) {
+	defer rows.Close()
+
+	var subs []models.WebhookSubscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscription: %w", err)
+		}
+		subs = append(subs, *sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read subscriptions: %w", err)
+	}
+
+	return subs, nil
+}
+
+func (r *SubscriptionRepository) Create(ctx context.Context, sub *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	now := time.Now()
+
+	query := `
+		INSERT INTO webhook_subscriptions (id, tenant_id, url, secret, min_severity, bbox, geometry, event_types, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING ` + subscriptionColumns
+
+	created, err := scanSubscription(r.db.QueryRow(ctx, query,
+		uuid.New(), sub.TenantID, sub.URL, sub.Secret, sub.MinSeverity,
+		sub.BBox, sub.Geometry, sub.EventTypes, true,
+		now, now,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create subscription: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r *SubscriptionRepository) GetByID(ctx context.Context, tenantID string, id uuid.UUID) (*models.WebhookSubscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM webhook_subscriptions WHERE id = $1 AND tenant_id = $2`
+
+	sub, err := scanSubscription(r.db.QueryRow(ctx, query, id, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("subscription not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	return sub, nil
+}
+
+func (r *SubscriptionRepository) List(ctx context.Context, tenantID string) ([]models.WebhookSubscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM webhook_subscriptions WHERE tenant_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+
+	return scanSubscriptions(rows)
+}
+
+// ListActive возвращает активные подписки всех арендаторов; отбор по арендатору события выполняет воркер
+func (r *SubscriptionRepository) ListActive(ctx context.Context) ([]models.WebhookSubscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM webhook_subscriptions WHERE is_active = true`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list active subscriptions: %w", err)
+	}
+
+	return scanSubscriptions(rows)
+}
+
+func (r *SubscriptionRepository) Update(ctx context.Context, sub *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	query := `
+		UPDATE webhook_subscriptions
+		SET url = $1, secret = $2, min_severity = $3, bbox = $4, geometry = $5, event_types = $6, is_active = $7, updated_at = $8
+		WHERE id = $9 AND tenant_id = $10
+		RETURNING ` + subscriptionColumns
+
+	updated, err := scanSubscription(r.db.QueryRow(ctx, query,
+		sub.URL, sub.Secret, sub.MinSeverity, sub.BBox, sub.Geometry, sub.EventTypes, sub.IsActive, time.Now(),
+		sub.ID, sub.TenantID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("subscription not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update subscription: %w", err)
+	}
+
+	return updated, nil
+}
+
+func (r *SubscriptionRepository) Delete(ctx context.Context, tenantID string, id uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1 AND tenant_id = $2`, id, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete subscription: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("subscription not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"geo_system_core/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TenantRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTenantRepository(db *pgxpool.Pool) *TenantRepository {
+	return &TenantRepository{db: db}
+}
+
+func (r *TenantRepository) Create(ctx context.Context, tenant models.Tenant) (*models.Tenant, error) {
+	query := `
+		INSERT INTO tenants (id, name) VALUES ($1, $2)
+		ON CONFLICT (id) DO NOTHING
+		RETURNING id, name, created_at
+	`
+
+	var created models.Tenant
+	err := r.db.QueryRow(ctx, query, tenant.ID, tenant.Name).Scan(&created.ID, &created.Name, &created.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("tenant already exists")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tenant: %w", err)
+	}
+
+	return &created, nil
+}
+
+func (r *TenantRepository) List(ctx context.Context) ([]models.Tenant, error) {
+	rows, err := r.db.Query(ctx, `SELECT id, name, created_at FROM tenants ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+	defer rows.Close()
+
+	tenants := []models.Tenant{}
+	for rows.Next() {
+		var tenant models.Tenant
+		if err := rows.Scan(&tenant.ID, &tenant.Name, &tenant.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant: %w", err)
+		}
+		tenants = append(tenants, tenant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tenants: %w", err)
+	}
+
+	return tenants, nil
+}
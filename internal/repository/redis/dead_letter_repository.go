@@ -0,0 +1,215 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"geo_system_core/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// DeadLetterRepository хранит доставки вебхуков, для которых исчерпаны попытки отправки.
+// Записи лежат в hash по ID, а порядок по времени ошибки — в sorted set; у каждого арендатора свои ключи.
+type DeadLetterRepository struct {
+	client *redis.Client
+}
+
+func NewDeadLetterRepository(client *redis.Client) *DeadLetterRepository {
+	return &DeadLetterRepository{client: client}
+}
+
+const (
+	deadLetterKeyPrefix   = "webhook:dead:"
+	deadLetterTxRetries   = 5
+	errDeadLetterNotFound = "dead letter not found"
+)
+
+// deadLetterKeys возвращает ключи hash и sorted set арендатора.
+// Для default сохранены ключи, существовавшие до появления арендаторов.
+func deadLetterKeys(tenantID string) (items, index string) {
+	prefix := deadLetterKeyPrefix
+	if tenantID != "" && tenantID != models.DefaultTenant {
+		prefix += tenantID + ":"
+	}
+	return prefix + "items", prefix + "index"
+}
+
+func (r *DeadLetterRepository) Add(ctx context.Context, dl models.DeadLetter) error {
+	itemsKey, indexKey := deadLetterKeys(dl.TenantID)
+	data, err := json.Marshal(dl)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, itemsKey, dl.ID.String(), data)
+		pipe.ZAdd(ctx, indexKey, redis.Z{Score: float64(dl.FailedAt.UnixMilli()), Member: dl.ID.String()})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save dead letter: %w", err)
+	}
+
+	return nil
+}
+
+// List возвращает доставки начиная с последних
+func (r *DeadLetterRepository) List(ctx context.Context, tenantID string, offset, limit int) ([]models.DeadLetter, int, error) {
+	itemsKey, indexKey := deadLetterKeys(tenantID)
+	total, err := r.client.ZCard(ctx, indexKey).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count dead letters: %w", err)
+	}
+
+	ids, err := r.client.ZRevRange(ctx, indexKey, int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	if len(ids) == 0 {
+		return []models.DeadLetter{}, int(total), nil
+	}
+
+	values, err := r.client.HMGet(ctx, itemsKey, ids...).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get dead letters: %w", err)
+	}
+
+	deadLetters := make([]models.DeadLetter, 0, len(values))
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue // запись удалена между чтениями
+		}
+		var dl models.DeadLetter
+		if err := json.Unmarshal([]byte(data), &dl); err != nil {
+			return nil, 0, fmt.Errorf("failed to unmarshal dead letter: %w", err)
+		}
+		deadLetters = append(deadLetters, dl)
+	}
+
+	return deadLetters, int(total), nil
+}
+
+// IDs возвращает идентификаторы всех доставок от старых к новым
+func (r *DeadLetterRepository) IDs(ctx context.Context, tenantID string) ([]uuid.UUID, error) {
+	_, indexKey := deadLetterKeys(tenantID)
+	members, err := r.client.ZRange(ctx, indexKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	ids := make([]uuid.UUID, 0, len(members))
+	for _, member := range members {
+		id, err := uuid.Parse(member)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (r *DeadLetterRepository) GetByID(ctx context.Context, tenantID string, id uuid.UUID) (*models.DeadLetter, error) {
+	itemsKey, _ := deadLetterKeys(tenantID)
+	data, err := r.client.HGet(ctx, itemsKey, id.String()).Result()
+	if err == redis.Nil {
+		return nil, fmt.Errorf(errDeadLetterNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+
+	var dl models.DeadLetter
+	if err := json.Unmarshal([]byte(data), &dl); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dead letter: %w", err)
+	}
+
+	return &dl, nil
+}
+
+// Requeue в одной транзакции возвращает доставку в очередь вебхуков, адресуя ее
+// исходному получателю, и удаляет из dead-letter. WATCH не дает поставить одну доставку дважды
+// при параллельных запросах.
+func (r *DeadLetterRepository) Requeue(ctx context.Context, tenantID string, id uuid.UUID) error {
+	itemsKey, indexKey := deadLetterKeys(tenantID)
+	txf := func(tx *redis.Tx) error {
+		data, err := tx.HGet(ctx, itemsKey, id.String()).Result()
+		if err == redis.Nil {
+			return fmt.Errorf(errDeadLetterNotFound)
+		}
+		if err != nil {
+			return err
+		}
+
+		var dl models.DeadLetter
+		if err := json.Unmarshal([]byte(data), &dl); err != nil {
+			return fmt.Errorf("failed to unmarshal dead letter: %w", err)
+		}
+		job, err := json.Marshal(models.WebhookJob{ID: uuid.New(), Payload: dl.Payload, SubscriptionID: &dl.SubscriptionID})
+		if err != nil {
+			return fmt.Errorf("failed to marshal webhook payload: %w", err)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.LPush(ctx, webhookQueueKey, job)
+			pipe.HDel(ctx, itemsKey, id.String())
+			pipe.ZRem(ctx, indexKey, id.String())
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < deadLetterTxRetries; i++ {
+		err := r.client.Watch(ctx, txf, itemsKey)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			if err.Error() == errDeadLetterNotFound {
+				return err
+			}
+			return fmt.Errorf("failed to requeue dead letter: %w", err)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("failed to requeue dead letter: too many concurrent updates")
+}
+
+func (r *DeadLetterRepository) Delete(ctx context.Context, tenantID string, id uuid.UUID) error {
+	itemsKey, indexKey := deadLetterKeys(tenantID)
+	var deleted *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		deleted = pipe.HDel(ctx, itemsKey, id.String())
+		pipe.ZRem(ctx, indexKey, id.String())
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+	if deleted.Val() == 0 {
+		return fmt.Errorf(errDeadLetterNotFound)
+	}
+
+	return nil
+}
+
+// Purge удаляет все доставки арендатора и возвращает их количество
+func (r *DeadLetterRepository) Purge(ctx context.Context, tenantID string) (int, error) {
+	itemsKey, indexKey := deadLetterKeys(tenantID)
+	var count *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.HLen(ctx, itemsKey)
+		pipe.Del(ctx, itemsKey, indexKey)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge dead letters: %w", err)
+	}
+
+	return int(count.Val()), nil
+}
@@ -0,0 +1,140 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"geo_system_core/internal/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	incidentEventsStreamPrefix = "incident:events:"
+	incidentEventsChannel      = "incident:events"
+)
+
+// EventRepository хранит события инцидентов в потоке Redis арендатора для возобновления чтения
+// и рассылает их всем репликам через pub/sub
+type EventRepository struct {
+	client    *redis.Client
+	streamLen int64
+}
+
+// NewEventRepository создает репозиторий; streamLen — сколько последних событий арендатора хранится для возобновления
+func NewEventRepository(client *redis.Client, streamLen int64) *EventRepository {
+	return &EventRepository{client: client, streamLen: streamLen}
+}
+
+func incidentEventsStream(tenantID string) string {
+	return incidentEventsStreamPrefix + tenantID
+}
+
+// Publish добавляет событие в поток арендатора, заполняя event.ID, и рассылает его подписчикам.
+// Если рассылка не удалась, событие остается в потоке и будет доставлено при возобновлении.
+func (r *EventRepository) Publish(ctx context.Context, event *models.IncidentEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal incident event: %w", err)
+	}
+
+	id, err := r.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: incidentEventsStream(event.TenantID),
+		MaxLen: r.streamLen,
+		Approx: true,
+		Values: map[string]interface{}{"data": data},
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to append incident event: %w", err)
+	}
+	event.ID = id
+
+	data, err = json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal incident event: %w", err)
+	}
+	if err := r.client.Publish(ctx, incidentEventsChannel, data).Err(); err != nil {
+		return fmt.Errorf("failed to publish incident event: %w", err)
+	}
+
+	return nil
+}
+
+// Since возвращает не более count событий арендатора, записанных после события lastID
+func (r *EventRepository) Since(ctx context.Context, tenantID, lastID string, count int64) ([]models.IncidentEvent, error) {
+	messages, err := r.client.XRangeN(ctx, incidentEventsStream(tenantID), "("+lastID, "+", count).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read incident events: %w", err)
+	}
+
+	events := make([]models.IncidentEvent, 0, len(messages))
+	for _, message := range messages {
+		data, ok := message.Values["data"].(string)
+		if !ok {
+			continue
+		}
+		var event models.IncidentEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal incident event: %w", err)
+		}
+		event.ID = message.ID
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// FirstID возвращает идентификатор самого старого события арендатора, оставшегося в потоке; пустой — событий нет
+func (r *EventRepository) FirstID(ctx context.Context, tenantID string) (string, error) {
+	messages, err := r.client.XRangeN(ctx, incidentEventsStream(tenantID), "-", "+", 1).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to read first incident event: %w", err)
+	}
+	if len(messages) == 0 {
+		return "", nil
+	}
+
+	return messages[0].ID, nil
+}
+
+// LastID возвращает идентификатор последнего события арендатора; пустой — событий нет
+func (r *EventRepository) LastID(ctx context.Context, tenantID string) (string, error) {
+	messages, err := r.client.XRevRangeN(ctx, incidentEventsStream(tenantID), "+", "-", 1).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to read last incident event: %w", err)
+	}
+	if len(messages) == 0 {
+		return "", nil
+	}
+
+	return messages[0].ID, nil
+}
+
+// Subscribe передает в handle события всех арендаторов, пока не отменен ctx.
+// После обрыва соединения клиент Redis переподписывается сам; пропущенные за это время события
+// подписчики получают при возобновлении по Last-Event-ID.
+func (r *EventRepository) Subscribe(ctx context.Context, handle func(event models.IncidentEvent)) error {
+	pubsub := r.client.Subscribe(ctx, incidentEventsChannel)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe to incident events: %w", err)
+	}
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			var event models.IncidentEvent
+			if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+				continue // сообщение не от этого сервиса
+			}
+			handle(event)
+		}
+	}
+}
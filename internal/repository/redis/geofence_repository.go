@@ -0,0 +1,99 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"geo_system_core/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// GeofenceRepository хранит для каждого пользователя арендатора набор зон, в которых он находится
+type GeofenceRepository struct {
+	client *redis.Client
+}
+
+func NewGeofenceRepository(client *redis.Client) *GeofenceRepository {
+	return &GeofenceRepository{client: client}
+}
+
+const (
+	geofenceKeyPrefix     = "geofence:user:"
+	geofenceUpdateRetries = 5
+)
+
+// geofenceKey возвращает ключ состояния пользователя; для default сохранен формат
+// без арендатора, чтобы не потерять состояние, записанное до появления арендаторов
+func geofenceKey(tenantID, userID string) string {
+	if tenantID == "" || tenantID == models.DefaultTenant {
+		return geofenceKeyPrefix + userID
+	}
+	return geofenceKeyPrefix + tenantID + ":" + userID
+}
+
+// UpdateUserZones читает текущий набор зон пользователя, передает его в update и сохраняет результат.
+// Запись выполняется в транзакции с WATCH: при параллельной проверке того же пользователя
+// update будет вызван повторно с актуальным состоянием.
+func (r *GeofenceRepository) UpdateUserZones(
+	ctx context.Context,
+	tenantID string,
+	userID string,
+	ttl time.Duration,
+	update func(zones map[uuid.UUID]models.ZoneState) map[uuid.UUID]models.ZoneState,
+) error {
+	key := geofenceKey(tenantID, userID)
+
+	txf := func(tx *redis.Tx) error {
+		fields, err := tx.HGetAll(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+
+		zones := make(map[uuid.UUID]models.ZoneState, len(fields))
+		for _, data := range fields {
+			var state models.ZoneState
+			if err := json.Unmarshal([]byte(data), &state); err != nil {
+				return fmt.Errorf("failed to unmarshal zone state: %w", err)
+			}
+			zones[state.IncidentID] = state
+		}
+
+		next := update(zones)
+
+		values := make([]interface{}, 0, len(next)*2)
+		for id, state := range next {
+			data, err := json.Marshal(state)
+			if err != nil {
+				return fmt.Errorf("failed to marshal zone state: %w", err)
+			}
+			values = append(values, id.String(), data)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, key)
+			if len(values) > 0 {
+				pipe.HSet(ctx, key, values...)
+				pipe.Expire(ctx, key, ttl)
+			}
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < geofenceUpdateRetries; i++ {
+		err := r.client.Watch(ctx, txf, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update user zones: %w", err)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("failed to update user zones: too many concurrent updates")
+}
@@ -3,10 +3,13 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"geo_system_core/internal/models"
+	"strconv"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -19,12 +22,16 @@ func NewQueueRepository(client *redis.Client) *QueueRepository {
 }
 
 const (
-	webhookQueueKey = "webhook:queue"
-	cacheKeyPrefix  = "incidents:active"
+	webhookQueueKey      = "webhook:queue"
+	webhookProcessingKey = "webhook:processing"           // элементы, которые сейчас доставляет какой-либо воркер
+	webhookDeadlinesKey  = "webhook:processing:deadlines" // срок, до которого элемент должен быть подтвержден
+	webhookTxRetries     = 5
+	cacheKeyPrefix       = "incidents:active"
+	cacheVersionKey      = "incidents:active:version" // увеличивается при каждом сбросе снимка
 )
 
 func (r *QueueRepository) EnqueueWebhook(ctx context.Context, payload models.WebhookPayload) error {
-	data, err := json.Marshal(payload)
+	data, err := json.Marshal(models.WebhookJob{ID: uuid.New(), Payload: payload})
 	if err != nil {
 		return fmt.Errorf("failed to marshal webhook payload: %w", err)
 	}
@@ -37,8 +44,11 @@ func (r *QueueRepository) EnqueueWebhook(ctx context.Context, payload models.Web
 	return nil
 }
 
-func (r *QueueRepository) DequeueWebhook(ctx context.Context) (*models.WebhookPayload, error) {
-	result, err := r.client.BRPop(ctx, 5*time.Second, webhookQueueKey).Result()
+// DequeueWebhook атомарно переносит элемент из очереди в список обработки и назначает ему
+// срок подтверждения visibilityTimeout. Элемент остается в Redis до AckWebhook, поэтому
+// падение воркера во время доставки не теряет событие: RequeueStaleWebhooks вернет его в очередь.
+func (r *QueueRepository) DequeueWebhook(ctx context.Context, visibilityTimeout time.Duration) (*models.WebhookJob, error) {
+	data, err := r.client.BLMove(ctx, webhookQueueKey, webhookProcessingKey, "RIGHT", "LEFT", 5*time.Second).Result()
 	if err == redis.Nil {
 		return nil, nil // Очередь пуста
 	}
@@ -46,29 +56,201 @@ func (r *QueueRepository) DequeueWebhook(ctx context.Context) (*models.WebhookPa
 		return nil, fmt.Errorf("failed to dequeue webhook: %w", err)
 	}
 
-	var payload models.WebhookPayload
-	err = json.Unmarshal([]byte(result[1]), &payload)
+	deadline := time.Now().Add(visibilityTimeout)
+	err = r.client.ZAdd(ctx, webhookDeadlinesKey, redis.Z{Score: float64(deadline.UnixMilli()), Member: data}).Err()
 	if err != nil {
+		return nil, fmt.Errorf("failed to set webhook deadline: %w", err)
+	}
+
+	job, err := decodeWebhookJob(data)
+	if err != nil {
+		// Нечитаемый элемент не удастся обработать и при повторе
+		_ = r.AckWebhook(ctx, data)
+		return nil, err
+	}
+	job.Raw = data
+
+	return job, nil
+}
+
+// AckWebhook удаляет обработанный элемент из списка обработки
+func (r *QueueRepository) AckWebhook(ctx context.Context, raw string) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LRem(ctx, webhookProcessingKey, 1, raw)
+		pipe.ZRem(ctx, webhookDeadlinesKey, raw)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to ack webhook: %w", err)
+	}
+
+	return nil
+}
+
+// RequeueStaleWebhooks возвращает в начало очереди элементы, не подтвержденные в срок.
+// Элементам без срока (воркер упал между переносом и назначением срока) срок назначается сейчас.
+// Возвращает количество возвращенных элементов.
+func (r *QueueRepository) RequeueStaleWebhooks(ctx context.Context, visibilityTimeout time.Duration) (int, error) {
+	now := time.Now()
+
+	processing, err := r.client.LRange(ctx, webhookProcessingKey, 0, -1).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list processing webhooks: %w", err)
+	}
+	for _, data := range processing {
+		deadline := now.Add(visibilityTimeout)
+		err := r.client.ZAddNX(ctx, webhookDeadlinesKey, redis.Z{Score: float64(deadline.UnixMilli()), Member: data}).Err()
+		if err != nil {
+			return 0, fmt.Errorf("failed to set webhook deadline: %w", err)
+		}
+	}
+
+	stale, err := r.client.ZRangeByScore(ctx, webhookDeadlinesKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list stale webhooks: %w", err)
+	}
+
+	requeued := 0
+	for _, data := range stale {
+		ok, err := r.requeueStale(ctx, data)
+		if err != nil {
+			return requeued, err
+		}
+		if ok {
+			requeued++
+		}
+	}
+
+	return requeued, nil
+}
+
+// requeueStale переносит элемент в очередь в транзакции с WATCH, чтобы не вернуть элемент,
+// который воркер подтвердил в этот момент, и не вернуть его дважды из нескольких реплик
+func (r *QueueRepository) requeueStale(ctx context.Context, data string) (bool, error) {
+	requeued := false
+	txf := func(tx *redis.Tx) error {
+		requeued = false
+		_, err := tx.LPos(ctx, webhookProcessingKey, data, redis.LPosArgs{}).Result()
+		if err == redis.Nil {
+			// Элемент уже подтвержден, остался только срок
+			return tx.ZRem(ctx, webhookDeadlinesKey, data).Err()
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.LRem(ctx, webhookProcessingKey, 1, data)
+			pipe.ZRem(ctx, webhookDeadlinesKey, data)
+			pipe.RPush(ctx, webhookQueueKey, data)
+			return nil
+		})
+		requeued = err == nil
+		return err
+	}
+
+	for i := 0; i < webhookTxRetries; i++ {
+		err := r.client.Watch(ctx, txf, webhookProcessingKey)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to requeue stale webhook: %w", err)
+		}
+		return requeued, nil
+	}
+
+	return false, fmt.Errorf("failed to requeue stale webhook: too many concurrent updates")
+}
+
+// WebhookQueueLength возвращает количество событий в очереди и в обработке
+func (r *QueueRepository) WebhookQueueLength(ctx context.Context) (queued, processing int64, err error) {
+	var queuedCmd, processingCmd *redis.IntCmd
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		queuedCmd = pipe.LLen(ctx, webhookQueueKey)
+		processingCmd = pipe.LLen(ctx, webhookProcessingKey)
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get webhook queue length: %w", err)
+	}
+
+	return queuedCmd.Val(), processingCmd.Val(), nil
+}
+
+// decodeWebhookJob разбирает элемент очереди. Элементы, поставленные до появления WebhookJob,
+// содержат само событие без обертки.
+func decodeWebhookJob(data string) (*models.WebhookJob, error) {
+	var job models.WebhookJob
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal webhook payload: %w", err)
 	}
+	if job.Payload.Event == "" {
+		if err := json.Unmarshal([]byte(data), &job.Payload); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal webhook payload: %w", err)
+		}
+	}
+
+	return &job, nil
+}
+
+// ActiveIncidentsCacheVersion возвращает номер версии снимка, который увеличивается при каждом сбросе кэша.
+// Версию читают до запроса к Postgres и передают в CacheActiveIncidents.
+func (r *QueueRepository) ActiveIncidentsCacheVersion(ctx context.Context) (int64, error) {
+	version, err := r.client.Get(ctx, cacheVersionKey).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get incidents cache version: %w", err)
+	}
 
-	return &payload, nil
+	return version, nil
 }
 
-func (r *QueueRepository) CacheActiveIncidents(ctx context.Context, incidents []models.Incident, ttl time.Duration) error {
+// CacheActiveIncidents сохраняет снимок, только если с момента чтения version кэш никто не сбрасывал.
+// Иначе снимок мог быть прочитан до изменения другой реплики и не сохраняется (cached == false).
+func (r *QueueRepository) CacheActiveIncidents(ctx context.Context, incidents []models.Incident, ttl time.Duration, version int64) (cached bool, err error) {
+	if incidents == nil {
+		incidents = []models.Incident{} // пустой снимок — тоже попадание в кэш
+	}
 	data, err := json.Marshal(incidents)
 	if err != nil {
-		return fmt.Errorf("failed to marshal incidents: %w", err)
+		return false, fmt.Errorf("failed to marshal incidents: %w", err)
 	}
 
-	err = r.client.Set(ctx, cacheKeyPrefix, data, ttl).Err()
+	txf := func(tx *redis.Tx) error {
+		current, err := tx.Get(ctx, cacheVersionKey).Int64()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		if current != version {
+			return nil
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, cacheKeyPrefix, data, ttl)
+			return nil
+		})
+		cached = err == nil
+		return err
+	}
+
+	err = r.client.Watch(ctx, txf, cacheVersionKey)
+	if errors.Is(err, redis.TxFailedErr) {
+		return false, nil // кэш сброшен во время записи
+	}
 	if err != nil {
-		return fmt.Errorf("failed to cache incidents: %w", err)
+		return false, fmt.Errorf("failed to cache incidents: %w", err)
 	}
 
-	return nil
+	return cached, nil
 }
 
+// GetCachedActiveIncidents возвращает снимок; nil — кэш пуст
 func (r *QueueRepository) GetCachedActiveIncidents(ctx context.Context) ([]models.Incident, error) {
 	data, err := r.client.Get(ctx, cacheKeyPrefix).Result()
 	if err == redis.Nil {
@@ -86,3 +268,18 @@ func (r *QueueRepository) GetCachedActiveIncidents(ctx context.Context) ([]model
 
 	return incidents, nil
 }
+
+// InvalidateCachedActiveIncidents удаляет снимок и увеличивает его версию,
+// чтобы реплика, читающая Postgres в этот момент, не сохранила устаревшие данные
+func (r *QueueRepository) InvalidateCachedActiveIncidents(ctx context.Context) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, cacheVersionKey)
+		pipe.Del(ctx, cacheKeyPrefix)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to invalidate cached incidents: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package redis
+
+import (
+	"context"
+	"geo_system_core/internal/models"
+	"geo_system_core/internal/redistest"
+	"testing"
+	"time"
+)
+
+func newQueueRepository(t *testing.T) (*QueueRepository, *redistest.Server) {
+	server := redistest.NewServer(t)
+	return NewQueueRepository(server.NewClient(t)), server
+}
+
+func enqueue(t *testing.T, repo *QueueRepository, userID string) {
+	t.Helper()
+	payload := models.WebhookPayload{Event: "zone.enter", UserID: userID, Timestamp: time.Now()}
+	if err := repo.EnqueueWebhook(context.Background(), payload); err != nil {
+		t.Fatalf("EnqueueWebhook() error = %v", err)
+	}
+}
+
+func dequeue(t *testing.T, repo *QueueRepository, visibilityTimeout time.Duration) *models.WebhookJob {
+	t.Helper()
+	job, err := repo.DequeueWebhook(context.Background(), visibilityTimeout)
+	if err != nil {
+		t.Fatalf("DequeueWebhook() error = %v", err)
+	}
+	return job
+}
+
+func TestQueueDequeueOrder(t *testing.T) {
+	repo, server := newQueueRepository(t)
+	enqueue(t, repo, "first")
+	enqueue(t, repo, "second")
+
+	for _, expected := range []string{"first", "second"} {
+		job := dequeue(t, repo, time.Minute)
+		if job == nil || job.Payload.UserID != expected {
+			t.Fatalf("DequeueWebhook() = %+v, expected user %s", job, expected)
+		}
+		if _, ok := server.ZScore(webhookDeadlinesKey, job.Raw); !ok {
+			t.Errorf("DequeueWebhook() did not set a deadline for %s", expected)
+		}
+	}
+	if job := dequeue(t, repo, time.Minute); job != nil {
+		t.Errorf("DequeueWebhook() of empty queue = %+v, expected nil", job)
+	}
+
+	queued, processing, err := repo.WebhookQueueLength(context.Background())
+	if err != nil {
+		t.Fatalf("WebhookQueueLength() error = %v", err)
+	}
+	if queued != 0 || processing != 2 {
+		t.Errorf("WebhookQueueLength() = %d, %d, expected 0, 2", queued, processing)
+	}
+}
+
+func TestQueueRequeueStale(t *testing.T) {
+	tests := []struct {
+		name      string
+		ack       bool
+		expired   bool
+		redeliver bool
+		requeued  int
+	}{
+		{name: "Просроченный элемент доставляется повторно", expired: true, redeliver: true, requeued: 1},
+		{name: "Подтвержденный элемент не доставляется повторно", ack: true, expired: true},
+		{name: "Срок не истек", expired: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo, server := newQueueRepository(t)
+			enqueue(t, repo, "user-1")
+
+			job := dequeue(t, repo, time.Minute)
+			if job == nil {
+				t.Fatal("DequeueWebhook() = nil, expected job")
+			}
+			if tt.ack {
+				if err := repo.AckWebhook(ctx, job.Raw); err != nil {
+					t.Fatalf("AckWebhook() error = %v", err)
+				}
+			}
+			if tt.expired {
+				// Воркер не подтвердил элемент до срока
+				server.SetZScore(webhookDeadlinesKey, job.Raw, float64(time.Now().Add(-time.Second).UnixMilli()))
+			}
+
+			requeued, err := repo.RequeueStaleWebhooks(ctx, time.Minute)
+			if err != nil {
+				t.Fatalf("RequeueStaleWebhooks() error = %v", err)
+			}
+			if requeued != tt.requeued {
+				t.Errorf("RequeueStaleWebhooks() = %d, expected %d", requeued, tt.requeued)
+			}
+
+			again := dequeue(t, repo, time.Minute)
+			if (again != nil) != tt.redeliver {
+				t.Fatalf("DequeueWebhook() after requeue = %+v, expected redelivery: %v", again, tt.redeliver)
+			}
+			if again != nil && (again.ID != job.ID || again.Raw != job.Raw) {
+				t.Errorf("DequeueWebhook() after requeue = %s, expected the same job %s", again.ID, job.ID)
+			}
+			if tt.ack {
+				if processing := server.List(webhookProcessingKey); len(processing) != 0 {
+					t.Errorf("processing list after ack = %v, expected empty", processing)
+				}
+				if _, ok := server.ZScore(webhookDeadlinesKey, job.Raw); ok {
+					t.Error("deadline of acked job was not removed")
+				}
+			}
+		})
+	}
+}
+
+func TestQueueRequeueWithoutDeadline(t *testing.T) {
+	ctx := context.Background()
+	repo, server := newQueueRepository(t)
+	enqueue(t, repo, "user-1")
+	job := dequeue(t, repo, time.Minute)
+
+	// Воркер упал между переносом в список обработки и назначением срока
+	if err := repo.client.ZRem(ctx, webhookDeadlinesKey, job.Raw).Err(); err != nil {
+		t.Fatalf("failed to remove deadline: %v", err)
+	}
+
+	requeued, err := repo.RequeueStaleWebhooks(ctx, time.Minute)
+	if err != nil {
+		t.Fatalf("RequeueStaleWebhooks() error = %v", err)
+	}
+	if requeued != 0 {
+		t.Errorf("RequeueStaleWebhooks() = %d, expected 0 until the new deadline expires", requeued)
+	}
+	if _, ok := server.ZScore(webhookDeadlinesKey, job.Raw); !ok {
+		t.Fatal("RequeueStaleWebhooks() did not set a deadline")
+	}
+
+	server.SetZScore(webhookDeadlinesKey, job.Raw, float64(time.Now().Add(-time.Second).UnixMilli()))
+	requeued, err = repo.RequeueStaleWebhooks(ctx, time.Minute)
+	if err != nil {
+		t.Fatalf("RequeueStaleWebhooks() error = %v", err)
+	}
+	if requeued != 1 {
+		t.Errorf("RequeueStaleWebhooks() = %d, expected 1", requeued)
+	}
+}
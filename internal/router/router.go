@@ -2,59 +2,164 @@ package router
 
 import (
 	"context"
+	"geo_system_core/internal/auth"
 	"geo_system_core/internal/config"
 	"geo_system_core/internal/handler"
+	"geo_system_core/internal/metrics"
 	"geo_system_core/internal/middleware"
 	"geo_system_core/internal/repository/postgres"
 	"geo_system_core/internal/repository/redis"
 	"geo_system_core/internal/service"
+	"log"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func SetupRouter(
 	cfg *config.Config,
+	background *service.Background,
+	db *pgxpool.Pool,
 	incidentRepo *postgres.IncidentRepository,
 	locationRepo *postgres.LocationRepository,
 	queueRepo *redis.QueueRepository,
+	geofenceRepo *redis.GeofenceRepository,
+	deadLetterRepo *redis.DeadLetterRepository,
+	eventRepo *redis.EventRepository,
+	subscriptionRepo *postgres.SubscriptionRepository,
+	apiKeyRepo *postgres.APIKeyRepository,
+	tenantRepo *postgres.TenantRepository,
 ) *gin.Engine {
 	// Инициализация сервисов
-	incidentService := service.NewIncidentService(incidentRepo)
-	locationService := service.NewLocationService(incidentRepo, locationRepo, queueRepo)
+	incidentIndex := service.NewIncidentIndex(incidentRepo, queueRepo, cfg.Index.CacheTTL)
+	incidentEvents := service.NewIncidentEvents(eventRepo, cfg.Events.Heartbeat, background.Streams())
+	incidentService := service.NewIncidentService(incidentRepo, incidentIndex, incidentEvents)
+	geofenceService := service.NewGeofenceService(geofenceRepo, queueRepo, cfg.Geofence.DwellTime, cfg.Geofence.StateTTL)
+	locationService := service.NewLocationService(incidentRepo, locationRepo, incidentIndex, geofenceService, background, &cfg.Location)
+	trackingService := service.NewTrackingService(locationService, incidentEvents, cfg.Tracking.NearbyDistance)
 	statsService := service.NewStatsService(locationRepo, cfg.Stats.TimeWindowMinutes)
-	webhookService := service.NewWebhookService(queueRepo, &cfg.Webhook)
+	subscriptionService := service.NewSubscriptionService(subscriptionRepo)
+	webhookService := service.NewWebhookService(queueRepo, deadLetterRepo, subscriptionRepo, &cfg.Webhook)
+	deadLetterService := service.NewDeadLetterService(deadLetterRepo, webhookService)
+	healthService := service.NewHealthService(db, queueRepo, webhookService)
+	tenantService := service.NewTenantService(tenantRepo)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, tenantService, cfg.Auth.APIKey, background)
+	incidentScheduler := service.NewIncidentScheduler(incidentRepo, queueRepo, incidentEvents, cfg.Scheduler.Interval)
+
+	// Загружаем индекс активных инцидентов; при ошибке проверки координат
+	// обращаются к БД, пока индекс не загрузится при следующем обновлении
+	if err := incidentIndex.Load(context.Background()); err != nil {
+		log.Printf("incident index: %v", err)
+	}
+	background.Go(func(ctx context.Context) {
+		incidentIndex.StartRefresher(ctx, cfg.Index.RefreshInterval)
+	})
 
 	// Запускаем worker для обработки вебхуков
-	ctx := context.Background()
-	go webhookService.StartWorker(ctx)
+	background.Go(webhookService.StartWorker)
+	background.Go(webhookService.StartReaper)
+
+	// Получаем изменения инцидентов от всех реплик для потоков SSE
+	background.Go(incidentEvents.Start)
+
+	// Запускаем планировщик завершения инцидентов по expires_at
+	background.Go(incidentScheduler.Start)
 
 	// Инициализация handlers
 	incidentHandler := handler.NewIncidentHandler(incidentService)
 	locationHandler := handler.NewLocationHandler(locationService)
+	trackingHandler := handler.NewTrackingHandler(trackingService)
 	statsHandler := handler.NewStatsHandler(statsService)
-	healthHandler := handler.NewHealthHandler()
+	subscriptionHandler := handler.NewSubscriptionHandler(subscriptionService)
+	deadLetterHandler := handler.NewDeadLetterHandler(deadLetterService)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+	tenantHandler := handler.NewTenantHandler(tenantService)
+	healthHandler := handler.NewHealthHandler(healthService)
 
 	// Настройка роутера
 	r := gin.Default()
+	r.Use(middleware.Metrics())
+
+	// Метрики Prometheus (публичный, предназначен для внутренней сети)
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
 
 	// Health check (публичный)
 	r.GET("/api/v1/system/health", healthHandler.Health)
+	r.GET("/api/v1/system/live", healthHandler.Live)
+	r.GET("/api/v1/system/ready", healthHandler.Ready)
 
-	// Публичный эндпоинт для проверки координат
-	r.POST("/api/v1/location/check", locationHandler.Check)
+	// Публичные эндпоинты для проверки координат и маршрутов; арендатор — по ключу или заголовку X-Tenant-ID
+	resolveTenant := middleware.ResolveTenant(apiKeyService, tenantService)
+	r.POST("/api/v1/location/check", resolveTenant, locationHandler.Check)
+	r.POST("/api/v1/location/check/batch", resolveTenant, locationHandler.CheckBatch)
+	r.POST("/api/v1/location/route", resolveTenant, locationHandler.CheckRoute)
 
-	// Статистика (публичный)
-	r.GET("/api/v1/incidents/stats", statsHandler.GetStats)
+	requireAdmin := middleware.RequireScope(apiKeyService, tenantService, auth.ScopeAdmin)
+	requireRead := middleware.RequireScope(apiKeyService, tenantService, auth.ScopeIncidentsRead)
+	requireWrite := middleware.RequireScope(apiKeyService, tenantService, auth.ScopeIncidentsWrite)
+	requirePlatformAdmin := middleware.RequirePlatformAdmin()
 
-	// API для управления инцидентами (требует API-key)
+	// Статистика и журнал проверок координат (требуют stats:read)
+	requireStats := middleware.RequireScope(apiKeyService, tenantService, auth.ScopeStatsRead)
+	r.GET("/api/v1/incidents/stats", requireStats, statsHandler.GetStats)
+	r.GET("/api/v1/location/checks", requireStats, locationHandler.ListChecks)
+
+	// Непрерывное отслеживание позиции по WebSocket (требует incidents:read)
+	r.GET("/api/v1/location/track", requireRead, trackingHandler.Track)
+
+	// API для управления инцидентами (требует incidents:read или incidents:write)
 	api := r.Group("/api/v1/incidents")
-	api.Use(middleware.APIKeyAuth(cfg.Auth.APIKey))
 	{
-		api.POST("", incidentHandler.Create)
-		api.GET("", incidentHandler.List)
-		api.GET("/:id", incidentHandler.GetByID)
-		api.PUT("/:id", incidentHandler.Update)
-		api.DELETE("/:id", incidentHandler.Delete)
+		api.POST("", requireWrite, incidentHandler.Create)
+		api.GET("", requireRead, incidentHandler.List)
+		api.POST("/import", requireWrite, incidentHandler.Import)
+		api.GET("/export", requireRead, incidentHandler.Export)
+		api.GET("/events", requireRead, incidentHandler.Events)
+		api.GET("/:id", requireRead, incidentHandler.GetByID)
+		api.GET("/:id/history", requireRead, incidentHandler.History)
+		api.PUT("/:id", requireWrite, incidentHandler.Update)
+		api.DELETE("/:id", requireWrite, incidentHandler.Delete)
+	}
+
+	// Подписки на вебхуки (требует admin)
+	subscriptions := r.Group("/api/v1/webhooks/subscriptions")
+	subscriptions.Use(requireAdmin)
+	{
+		subscriptions.POST("", subscriptionHandler.Create)
+		subscriptions.GET("", subscriptionHandler.List)
+		subscriptions.GET("/:id", subscriptionHandler.GetByID)
+		subscriptions.PUT("/:id", subscriptionHandler.Update)
+		subscriptions.DELETE("/:id", subscriptionHandler.Delete)
+	}
+
+	// Доставки вебхуков, для которых исчерпаны попытки (требует admin)
+	deadLetters := r.Group("/api/v1/webhooks/dead-letters")
+	deadLetters.Use(requireAdmin)
+	{
+		deadLetters.GET("", deadLetterHandler.List)
+		deadLetters.DELETE("", deadLetterHandler.Purge)
+		deadLetters.POST("/replay", deadLetterHandler.ReplayAll)
+		deadLetters.GET("/:id", deadLetterHandler.GetByID)
+		deadLetters.POST("/:id/replay", deadLetterHandler.Replay)
+		deadLetters.DELETE("/:id", deadLetterHandler.Delete)
+	}
+
+	// Ключи API (требует admin)
+	apiKeys := r.Group("/api/v1/admin/api-keys")
+	apiKeys.Use(requireAdmin)
+	{
+		apiKeys.POST("", apiKeyHandler.Create)
+		apiKeys.GET("", apiKeyHandler.List)
+		apiKeys.POST("/:id/rotate", apiKeyHandler.Rotate)
+		apiKeys.DELETE("/:id", apiKeyHandler.Revoke)
+	}
+
+	// Арендаторы (требует admin арендатора default)
+	tenants := r.Group("/api/v1/admin/tenants")
+	tenants.Use(requireAdmin, requirePlatformAdmin)
+	{
+		tenants.POST("", tenantHandler.Create)
+		tenants.GET("", tenantHandler.List)
 	}
 
 	return r
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"geo_system_core/internal/auth"
+	"geo_system_core/internal/models"
+	"geo_system_core/internal/repository/postgres"
+	"log"
+	"time"
+)
+
+// lastUsedInterval — как часто обновлять last_used_at, чтобы не писать в БД на каждый запрос
+const lastUsedInterval = time.Minute
+
+var (
+	// ErrInvalidAPIKey — ошибка валидации параметров ключа
+	ErrInvalidAPIKey = errors.New("invalid api key request")
+	// ErrAPIKeyRevoked — действие невозможно для отозванного ключа
+	ErrAPIKeyRevoked = errors.New("api key is revoked")
+	// ErrTenantForbidden — ключ не может работать с данными другого арендатора
+	ErrTenantForbidden = errors.New("api key is not allowed to access this tenant")
+)
+
+// APIKeyService управляет ключами арендатора из контекста запроса
+type APIKeyService struct {
+	repo         *postgres.APIKeyRepository
+	tenants      *TenantService
+	bootstrapKey string
+	background   *Background
+}
+
+// NewAPIKeyService создает сервис ключей. bootstrapKey (API_KEY) — начальный ключ арендатора default
+// с областью admin для выпуска первых ключей; пустой — отключен.
+func NewAPIKeyService(repo *postgres.APIKeyRepository, tenants *TenantService, bootstrapKey string, background *Background) *APIKeyService {
+	return &APIKeyService{repo: repo, tenants: tenants, bootstrapKey: bootstrapKey, background: background}
+}
+
+// Issue выпускает ключ арендатору из контекста запроса. Ключ для другого арендатора
+// (tenant_id в запросе) может выпустить только администратор платформы.
+func (s *APIKeyService) Issue(ctx context.Context, req models.CreateAPIKeyRequest) (*models.IssuedAPIKeyResponse, error) {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidAPIKey)
+	}
+
+	tenantID := auth.TenantFromContext(ctx)
+	if req.TenantID != "" && req.TenantID != tenantID {
+		if principal := auth.FromContext(ctx); principal == nil || !principal.IsPlatformAdmin() {
+			return nil, ErrTenantForbidden
+		}
+		exists, err := s.tenants.Exists(ctx, req.TenantID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("%w: tenant %s does not exist", ErrInvalidAPIKey, req.TenantID)
+		}
+		tenantID = req.TenantID
+	}
+
+	key, record, err := newAPIKey(tenantID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.Create(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.IssuedAPIKeyResponse{APIKey: *created, Key: key}, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context) ([]models.APIKey, error) {
+	return s.repo.List(ctx, auth.TenantFromContext(ctx))
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, id string) (*models.APIKey, error) {
+	uuid, err := parseUUID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Revoke(ctx, auth.TenantFromContext(ctx), uuid)
+}
+
+// Rotate выпускает новый ключ с теми же именем, областями и сроком;
+// старый ключ действует еще GracePeriodSeconds секунд
+func (s *APIKeyService) Rotate(ctx context.Context, id string, req models.RotateAPIKeyRequest) (*models.IssuedAPIKeyResponse, error) {
+	uuid, err := parseUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	old, err := s.repo.GetByID(ctx, auth.TenantFromContext(ctx), uuid)
+	if err != nil {
+		return nil, err
+	}
+	if old.RevokedAt != nil {
+		return nil, ErrAPIKeyRevoked
+	}
+
+	key, record, err := newAPIKey(old.TenantID, old.Name, old.Scopes, old.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	graceUntil := time.Now().Add(time.Duration(req.GracePeriodSeconds) * time.Second)
+	created, err := s.repo.Rotate(ctx, old.ID, record, graceUntil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.IssuedAPIKeyResponse{APIKey: *created, Key: key}, nil
+}
+
+// Authenticate находит ключ и проверяет, что он не отозван и не истек.
+// Хеши сравниваются за постоянное время.
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (*auth.Principal, error) {
+	if key == "" {
+		return nil, auth.ErrUnauthorized
+	}
+	if s.bootstrapKey != "" && auth.Equal(key, s.bootstrapKey) {
+		return &auth.Principal{TenantID: models.DefaultTenant, Name: "bootstrap", Scopes: []string{auth.ScopeAdmin}}, nil
+	}
+
+	prefix, ok := auth.ParsePrefix(key)
+	if !ok {
+		return nil, auth.ErrUnauthorized
+	}
+	record, err := s.repo.GetByPrefix(ctx, prefix)
+	if err != nil {
+		if err.Error() == "api key not found" {
+			return nil, auth.ErrUnauthorized
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if !auth.Equal(auth.HashKey(key), record.KeyHash) {
+		return nil, auth.ErrUnauthorized
+	}
+	if record.RevokedAt != nil || (record.ExpiresAt != nil && !now.Before(*record.ExpiresAt)) {
+		return nil, auth.ErrUnauthorized
+	}
+
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) >= lastUsedInterval {
+		s.background.Run(func(ctx context.Context) {
+			if err := s.repo.TouchLastUsed(ctx, record.ID, now); err != nil {
+				log.Printf("api keys: %v", err)
+			}
+		})
+	}
+
+	return &auth.Principal{KeyID: record.ID, TenantID: record.TenantID, Name: record.Name, Scopes: record.Scopes}, nil
+}
+
+func newAPIKey(tenantID, name string, scopes []string, expiresAt *time.Time) (string, *models.APIKey, error) {
+	key, prefix, hash, err := auth.GenerateKey()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	return key, &models.APIKey{
+		TenantID:  tenantID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}, nil
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"sync"
+)
+
+// Background запускает фоновые горутины сервиса и дожидается их при остановке.
+// Циклы (воркеры, периодические обновления) останавливаются сразу при Shutdown,
+// а разовые задачи (запись проверки, события зон) дорабатывают, пока не истечет
+// время на остановку, после чего их контекст тоже отменяется.
+type Background struct {
+	loopCtx    context.Context
+	stopLoops  context.CancelFunc
+	taskCtx    context.Context
+	abortTasks context.CancelFunc
+	loops      sync.WaitGroup
+	tasks      sync.WaitGroup
+
+	streams     chan struct{}
+	streamsOnce sync.Once
+}
+
+func NewBackground() *Background {
+	b := &Background{}
+	b.loopCtx, b.stopLoops = context.WithCancel(context.Background())
+	b.taskCtx, b.abortTasks = context.WithCancel(context.Background())
+	b.streams = make(chan struct{})
+	return b
+}
+
+// Streams возвращает канал, который закрывается в начале остановки, еще до ожидания HTTP-запросов.
+// По нему завершаются бесконечные ответы (потоки событий), иначе остановка ждала бы их до таймаута.
+func (b *Background) Streams() <-chan struct{} {
+	return b.streams
+}
+
+// CloseStreams закрывает канал Streams; повторный вызов ничего не делает
+func (b *Background) CloseStreams() {
+	b.streamsOnce.Do(func() { close(b.streams) })
+}
+
+// Go запускает цикл, который должен завершиться при отмене ctx
+func (b *Background) Go(loop func(ctx context.Context)) {
+	b.loops.Add(1)
+	go func() {
+		defer b.loops.Done()
+		loop(b.loopCtx)
+	}()
+}
+
+// Run запускает разовую задачу, результат которой не нужен вызывающему
+func (b *Background) Run(task func(ctx context.Context)) {
+	b.tasks.Add(1)
+	go func() {
+		defer b.tasks.Done()
+		task(b.taskCtx)
+	}()
+}
+
+// Shutdown останавливает циклы и ждет завершения всех горутин.
+// Если ctx истекает раньше, оставшиеся задачи отменяются и возвращается ошибка ctx.
+func (b *Background) Shutdown(ctx context.Context) error {
+	b.CloseStreams()
+	b.stopLoops()
+
+	done := make(chan struct{})
+	go func() {
+		b.loops.Wait()
+		b.tasks.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		b.abortTasks()
+		return nil
+	case <-ctx.Done():
+		b.abortTasks()
+		return ctx.Err()
+	}
+}
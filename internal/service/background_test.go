@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackgroundShutdown(t *testing.T) {
+	tests := []struct {
+		name        string
+		taskTime    time.Duration
+		timeout     time.Duration
+		expectedErr error
+	}{
+		{
+			name:        "Задачи успевают завершиться",
+			taskTime:    10 * time.Millisecond,
+			timeout:     time.Second,
+			expectedErr: nil,
+		},
+		{
+			name:        "Задачи отменяются по истечении времени",
+			taskTime:    time.Hour,
+			timeout:     20 * time.Millisecond,
+			expectedErr: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBackground()
+
+			loopStopped := make(chan struct{})
+			b.Go(func(ctx context.Context) {
+				<-ctx.Done()
+				close(loopStopped)
+			})
+
+			taskAborted := make(chan struct{})
+			b.Run(func(ctx context.Context) {
+				select {
+				case <-time.After(tt.taskTime):
+				case <-ctx.Done():
+					close(taskAborted)
+				}
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+
+			if err := b.Shutdown(ctx); !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("Shutdown() error = %v, expected %v", err, tt.expectedErr)
+			}
+
+			select {
+			case <-loopStopped:
+			default:
+				t.Errorf("loop was not stopped")
+			}
+			if tt.expectedErr != nil {
+				select {
+				case <-taskAborted:
+				case <-time.After(time.Second):
+					t.Errorf("task context was not cancelled")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"geo_system_core/internal/models"
+)
+
+// ErrInvalidCursor — курсор поврежден или не может быть применен к запросу
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor упаковывает позицию в непрозрачную для клиента строку
+func encodeCursor(cursor models.Cursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor разбирает курсор из запроса; пустая строка — первая страница
+func decodeCursor(value string) (*models.Cursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor models.Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"errors"
+	"geo_system_core/internal/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	cursor := models.Cursor{CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.UTC), ID: uuid.New()}
+
+	tests := []struct {
+		name     string
+		value    string
+		expected *models.Cursor
+		invalid  bool
+	}{
+		{name: "Первая страница", value: "", expected: nil},
+		{name: "Курсор из ответа", value: encodeCursor(cursor), expected: &cursor},
+		{name: "Не base64", value: "!!!", invalid: true},
+		{name: "Не JSON", value: "bm90LWpzb24", invalid: true},
+		{name: "Без времени", value: encodeCursor(models.Cursor{ID: cursor.ID}), invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := decodeCursor(tt.value)
+			if errors.Is(err, ErrInvalidCursor) != tt.invalid {
+				t.Fatalf("decodeCursor() error = %v, expected invalid: %v", err, tt.invalid)
+			}
+			if tt.invalid {
+				return
+			}
+			if (decoded == nil) != (tt.expected == nil) {
+				t.Fatalf("decodeCursor() = %v, expected %v", decoded, tt.expected)
+			}
+			if decoded != nil && (!decoded.CreatedAt.Equal(tt.expected.CreatedAt) || decoded.ID != tt.expected.ID) {
+				t.Errorf("decodeCursor() = %+v, expected %+v", *decoded, *tt.expected)
+			}
+		})
+	}
+}
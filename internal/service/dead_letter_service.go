@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"geo_system_core/internal/auth"
+	"geo_system_core/internal/models"
+	"geo_system_core/internal/repository/redis"
+)
+
+// ErrReplayTargetGone — получатель доставки удален, и повторить ее некому
+var ErrReplayTargetGone = errors.New("replay target is gone")
+
+// DeadLetterService управляет доставками вебхуков арендатора из контекста запроса,
+// для которых исчерпаны попытки отправки
+type DeadLetterService struct {
+	repo    *redis.DeadLetterRepository
+	webhook *WebhookService
+}
+
+func NewDeadLetterService(repo *redis.DeadLetterRepository, webhook *WebhookService) *DeadLetterService {
+	return &DeadLetterService{repo: repo, webhook: webhook}
+}
+
+func (s *DeadLetterService) List(ctx context.Context, page, limit int) ([]models.DeadLetter, int, error) {
+	offset := (page - 1) * limit
+	return s.repo.List(ctx, auth.TenantFromContext(ctx), offset, limit)
+}
+
+func (s *DeadLetterService) GetByID(ctx context.Context, id string) (*models.DeadLetter, error) {
+	uuid, err := parseUUID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, auth.TenantFromContext(ctx), uuid)
+}
+
+// Replay возвращает доставку в очередь; она будет отправлена только исходному получателю
+func (s *DeadLetterService) Replay(ctx context.Context, id string) error {
+	uuid, err := parseUUID(id)
+	if err != nil {
+		return err
+	}
+
+	tenantID := auth.TenantFromContext(ctx)
+	dl, err := s.repo.GetByID(ctx, tenantID, uuid)
+	if err != nil {
+		return err
+	}
+	if _, err := s.webhook.target(ctx, tenantID, dl.SubscriptionID); err != nil {
+		return err
+	}
+
+	return s.repo.Requeue(ctx, tenantID, uuid)
+}
+
+// ReplayAll возвращает в очередь все доставки, получатели которых еще существуют
+func (s *DeadLetterService) ReplayAll(ctx context.Context) (*models.ReplayResponse, error) {
+	ids, err := s.repo.IDs(ctx, auth.TenantFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.ReplayResponse{}
+	for _, id := range ids {
+		err := s.Replay(ctx, id.String())
+		if errors.Is(err, ErrReplayTargetGone) {
+			response.Failed = append(response.Failed, id.String())
+			continue
+		}
+		if err != nil && err.Error() == "dead letter not found" {
+			continue // уже повторена или удалена параллельным запросом
+		}
+		if err != nil {
+			return nil, err
+		}
+		response.Replayed++
+	}
+
+	return response, nil
+}
+
+func (s *DeadLetterService) Delete(ctx context.Context, id string) error {
+	uuid, err := parseUUID(id)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, auth.TenantFromContext(ctx), uuid)
+}
+
+func (s *DeadLetterService) Purge(ctx context.Context) (int, error) {
+	return s.repo.Purge(ctx, auth.TenantFromContext(ctx))
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"geo_system_core/internal/models"
+	"geo_system_core/internal/repository/redis"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GeofenceService сравнивает зоны, в которых находится пользователь, с предыдущей проверкой
+// и ставит в очередь вебхуки о входе, выходе и длительном пребывании в зоне
+type GeofenceService struct {
+	repo      *redis.GeofenceRepository
+	queueRepo *redis.QueueRepository
+	dwellTime time.Duration
+	stateTTL  time.Duration
+}
+
+func NewGeofenceService(
+	repo *redis.GeofenceRepository,
+	queueRepo *redis.QueueRepository,
+	dwellTime time.Duration,
+	stateTTL time.Duration,
+) *GeofenceService {
+	return &GeofenceService{
+		repo:      repo,
+		queueRepo: queueRepo,
+		dwellTime: dwellTime,
+		stateTTL:  stateTTL,
+	}
+}
+
+// Process обновляет набор зон пользователя арендатора и ставит в очередь события по изменениям.
+// Арендатор передается явно: метод вызывается в фоне, вне контекста запроса.
+func (s *GeofenceService) Process(ctx context.Context, tenantID, userID string, lat, lng float64, zones []models.NearbyIncident) error {
+	now := time.Now()
+
+	var events []models.WebhookPayload
+	err := s.repo.UpdateUserZones(ctx, tenantID, userID, s.stateTTL, func(prev map[uuid.UUID]models.ZoneState) map[uuid.UUID]models.ZoneState {
+		var next map[uuid.UUID]models.ZoneState
+		events, next = diffZones(prev, zones, now, s.dwellTime)
+		return next
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		event.TenantID = tenantID
+		event.UserID = userID
+		event.Latitude = lat
+		event.Longitude = lng
+		event.Timestamp = now
+		if err := s.queueRepo.EnqueueWebhook(ctx, event); err != nil {
+			return fmt.Errorf("failed to enqueue %s event: %w", event.Event, err)
+		}
+	}
+
+	return nil
+}
+
+// diffZones вычисляет события по разнице между сохраненным и текущим набором зон
+// и возвращает новое состояние. Событие zone.dwell отправляется один раз за пребывание;
+// dwellTime <= 0 отключает его.
+func diffZones(
+	prev map[uuid.UUID]models.ZoneState,
+	current []models.NearbyIncident,
+	now time.Time,
+	dwellTime time.Duration,
+) ([]models.WebhookPayload, map[uuid.UUID]models.ZoneState) {
+	var events []models.WebhookPayload
+	next := make(map[uuid.UUID]models.ZoneState, len(current))
+
+	// Выходы из зон: порядок по ID, чтобы события были детерминированы
+	var exited []models.ZoneState
+	inside := make(map[uuid.UUID]bool, len(current))
+	for _, zone := range current {
+		inside[zone.ID] = true
+	}
+	for id, state := range prev {
+		if !inside[id] {
+			exited = append(exited, state)
+		}
+	}
+	sort.Slice(exited, func(i, j int) bool {
+		return exited[i].IncidentID.String() < exited[j].IncidentID.String()
+	})
+	for _, state := range exited {
+		enteredAt := state.EnteredAt
+		events = append(events, models.WebhookPayload{
+			Event:     models.EventZoneExit,
+			Zone:      &models.NearbyIncident{ID: state.IncidentID, Title: state.Title, Severity: state.Severity},
+			EnteredAt: &enteredAt,
+		})
+	}
+
+	for i := range current {
+		zone := current[i]
+		state, ok := prev[zone.ID]
+		if !ok {
+			state = models.ZoneState{IncidentID: zone.ID, EnteredAt: now}
+			events = append(events, models.WebhookPayload{Event: models.EventZoneEnter, Zone: &zone})
+		} else if dwellTime > 0 && !state.DwellSent && now.Sub(state.EnteredAt) >= dwellTime {
+			state.DwellSent = true
+			enteredAt := state.EnteredAt
+			events = append(events, models.WebhookPayload{Event: models.EventZoneDwell, Zone: &zone, EnteredAt: &enteredAt})
+		}
+
+		state.Title = zone.Title
+		state.Severity = zone.Severity
+		next[zone.ID] = state
+	}
+
+	return events, next
+}
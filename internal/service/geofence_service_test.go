@@ -0,0 +1,79 @@
+package service
+
+import (
+	"geo_system_core/internal/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestDiffZones(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	zoneA := models.NearbyIncident{ID: uuid.New(), Title: "A", Severity: "high"}
+	zoneB := models.NearbyIncident{ID: uuid.New(), Title: "B", Severity: "low"}
+
+	tests := []struct {
+		name     string
+		prev     map[uuid.UUID]models.ZoneState
+		current  []models.NearbyIncident
+		expected []string
+	}{
+		{
+			name:     "Первый вход в зону",
+			prev:     map[uuid.UUID]models.ZoneState{},
+			current:  []models.NearbyIncident{zoneA},
+			expected: []string{models.EventZoneEnter},
+		},
+		{
+			name: "Повторная проверка внутри зоны без событий",
+			prev: map[uuid.UUID]models.ZoneState{
+				zoneA.ID: {IncidentID: zoneA.ID, EnteredAt: now.Add(-time.Minute)},
+			},
+			current:  []models.NearbyIncident{zoneA},
+			expected: nil,
+		},
+		{
+			name: "Пребывание дольше порога",
+			prev: map[uuid.UUID]models.ZoneState{
+				zoneA.ID: {IncidentID: zoneA.ID, EnteredAt: now.Add(-10 * time.Minute)},
+			},
+			current:  []models.NearbyIncident{zoneA},
+			expected: []string{models.EventZoneDwell},
+		},
+		{
+			name: "Dwell отправляется один раз",
+			prev: map[uuid.UUID]models.ZoneState{
+				zoneA.ID: {IncidentID: zoneA.ID, EnteredAt: now.Add(-10 * time.Minute), DwellSent: true},
+			},
+			current:  []models.NearbyIncident{zoneA},
+			expected: nil,
+		},
+		{
+			name: "Переход из одной зоны в другую",
+			prev: map[uuid.UUID]models.ZoneState{
+				zoneA.ID: {IncidentID: zoneA.ID, EnteredAt: now.Add(-time.Minute)},
+			},
+			current:  []models.NearbyIncident{zoneB},
+			expected: []string{models.EventZoneExit, models.EventZoneEnter},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, next := diffZones(tt.prev, tt.current, now, 5*time.Minute)
+
+			if len(events) != len(tt.expected) {
+				t.Fatalf("diffZones() returned %d events, expected %d", len(events), len(tt.expected))
+			}
+			for i, event := range events {
+				if event.Event != tt.expected[i] {
+					t.Errorf("event %d = %s, expected %s", i, event.Event, tt.expected[i])
+				}
+			}
+			if len(next) != len(tt.current) {
+				t.Errorf("next state has %d zones, expected %d", len(next), len(tt.current))
+			}
+		})
+	}
+}
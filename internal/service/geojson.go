@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"geo_system_core/internal/auth"
+	"geo_system_core/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const defaultCircleSegments = 64
+
+// ImportGeoJSON создает инциденты из объектов FeatureCollection. В режиме upsert объект,
+// чей id (или properties.id) совпадает с UUID существующего инцидента, обновляет его.
+// Ошибка в одном объекте не прерывает импорт остальных.
+func (s *IncidentService) ImportGeoJSON(ctx context.Context, fc models.FeatureCollection, upsert bool) *models.ImportResponse {
+	return importFeatures(fc, func(feature models.Feature) (*models.Incident, string, error) {
+		return s.importFeature(ctx, feature, upsert)
+	})
+}
+
+// importFeatures импортирует объекты по одному функцией importFeature и собирает результаты
+func importFeatures(fc models.FeatureCollection, importFeature func(models.Feature) (*models.Incident, string, error)) *models.ImportResponse {
+	response := &models.ImportResponse{Results: make([]models.ImportResult, 0, len(fc.Features))}
+
+	for i, feature := range fc.Features {
+		result := models.ImportResult{Index: i}
+
+		incident, action, err := importFeature(feature)
+		if err != nil {
+			result.Error = err.Error()
+			response.Failed++
+		} else {
+			result.ID = &incident.ID
+			result.Action = action
+			if action == "created" {
+				response.Created++
+			} else {
+				response.Updated++
+			}
+		}
+
+		response.Results = append(response.Results, result)
+	}
+
+	return response
+}
+
+func (s *IncidentService) importFeature(ctx context.Context, feature models.Feature, upsert bool) (*models.Incident, string, error) {
+	req, err := featureToRequest(feature)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !upsert {
+		req.ID = uuid.Nil
+	} else if req.ID != uuid.Nil {
+		owner, isActive, err := s.repo.Owner(ctx, req.ID)
+		action, err := upsertAction(auth.TenantFromContext(ctx), req.ID, owner, isActive, err)
+		if err != nil {
+			return nil, "", err
+		}
+		if action == "updated" {
+			incident, err := s.Update(ctx, req.ID.String(), createToUpdate(req), nil)
+			return incident, action, err
+		}
+	}
+
+	incident, err := s.Create(ctx, req)
+	return incident, "created", err
+}
+
+// upsertAction решает, что делать с объектом, id которого указывает на инцидент: owner и isActive —
+// арендатор и активность инцидента с этим ID, lookupErr — ошибка их получения. Инцидент арендатора
+// обновляется, отсутствующий создается с этим ID. Удаленный или чужой инцидент не трогается:
+// его ID нельзя использовать повторно.
+func upsertAction(tenantID string, id uuid.UUID, owner string, isActive bool, lookupErr error) (string, error) {
+	if lookupErr != nil {
+		if lookupErr.Error() == "incident not found" {
+			return "created", nil
+		}
+		return "", lookupErr
+	}
+	if owner != tenantID {
+		return "", fmt.Errorf("%w: id %s is already used by another incident", ErrInvalidIncident, id)
+	}
+	if !isActive {
+		return "", fmt.Errorf("%w: incident %s is deleted; remove the id to import the feature as a new incident", ErrInvalidIncident, id)
+	}
+	return "updated", nil
+}
+
+// ExportGeoJSON выгружает активные инциденты. Окружности выгружаются точкой с radius в properties
+// либо, при circlesAsPolygons, аппроксимирующим полигоном из segments вершин.
+func (s *IncidentService) ExportGeoJSON(ctx context.Context, circlesAsPolygons bool, segments int) (*models.FeatureCollection, error) {
+	incidents, err := s.repo.GetActiveIncidents(ctx, auth.TenantFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return exportFeatures(incidents, circlesAsPolygons, segments)
+}
+
+func exportFeatures(incidents []models.Incident, circlesAsPolygons bool, segments int) (*models.FeatureCollection, error) {
+	if segments <= 0 {
+		segments = defaultCircleSegments
+	}
+
+	fc := &models.FeatureCollection{
+		Type:     "FeatureCollection",
+		Features: make([]models.Feature, 0, len(incidents)),
+	}
+
+	for _, incident := range incidents {
+		properties := map[string]interface{}{
+			"title":       incident.Title,
+			"description": incident.Description,
+			"severity":    incident.Severity,
+			"status":      incident.Status,
+			"created_at":  incident.CreatedAt,
+			"updated_at":  incident.UpdatedAt,
+		}
+		if incident.StartsAt != nil {
+			properties["starts_at"] = incident.StartsAt
+		}
+		if incident.ExpiresAt != nil {
+			properties["expires_at"] = incident.ExpiresAt
+		}
+
+		var geometry interface{}
+		switch {
+		case incident.Geometry != nil:
+			geometry = incident.Geometry
+		case circlesAsPolygons:
+			geometry = circleToPolygon(incident.Latitude, incident.Longitude, incident.Radius, segments)
+			properties["radius"] = incident.Radius
+		default:
+			geometry = map[string]interface{}{
+				"type":        "Point",
+				"coordinates": models.Position{incident.Longitude, incident.Latitude},
+			}
+			properties["radius"] = incident.Radius
+		}
+
+		data, err := json.Marshal(geometry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal geometry of incident %s: %w", incident.ID, err)
+		}
+
+		fc.Features = append(fc.Features, models.Feature{
+			Type:       "Feature",
+			ID:         incident.ID.String(),
+			Geometry:   data,
+			Properties: properties,
+		})
+	}
+
+	return fc, nil
+}
+
+// featureToRequest сопоставляет объект GeoJSON с запросом на создание инцидента.
+// Поддерживаемые свойства: title (или name), description, severity, status, radius,
+// starts_at и expires_at (RFC 3339).
+func featureToRequest(feature models.Feature) (models.CreateIncidentRequest, error) {
+	var req models.CreateIncidentRequest
+
+	if feature.Type != "Feature" {
+		return req, fmt.Errorf("%w: unexpected object type %q", ErrInvalidIncident, feature.Type)
+	}
+
+	req.ID = featureID(feature)
+	req.Title = stringProperty(feature.Properties, "title")
+	if req.Title == "" {
+		req.Title = stringProperty(feature.Properties, "name")
+	}
+	req.Description = stringProperty(feature.Properties, "description")
+	req.Severity = stringProperty(feature.Properties, "severity")
+	req.Status = stringProperty(feature.Properties, "status")
+
+	if req.Title == "" {
+		return req, fmt.Errorf("%w: property title is required", ErrInvalidIncident)
+	}
+	switch req.Severity {
+	case "low", "medium", "high", "critical":
+	default:
+		return req, fmt.Errorf("%w: property severity must be one of low, medium, high, critical", ErrInvalidIncident)
+	}
+	switch req.Status {
+	case "", "active", "resolved":
+	default:
+		return req, fmt.Errorf("%w: property status must be active or resolved", ErrInvalidIncident)
+	}
+	for key, target := range map[string]**time.Time{"starts_at": &req.StartsAt, "expires_at": &req.ExpiresAt} {
+		value := stringProperty(feature.Properties, key)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return req, fmt.Errorf("%w: property %s must be an RFC 3339 timestamp", ErrInvalidIncident, key)
+		}
+		*target = &t
+	}
+
+	var header struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(feature.Geometry, &header); err != nil {
+		return req, fmt.Errorf("%w: invalid geometry: %v", ErrInvalidIncident, err)
+	}
+
+	switch header.Type {
+	case "Point":
+		var point models.Position
+		if err := json.Unmarshal(header.Coordinates, &point); err != nil {
+			return req, fmt.Errorf("%w: invalid point coordinates: %v", ErrInvalidIncident, err)
+		}
+		radius, ok := feature.Properties["radius"].(float64)
+		if !ok {
+			return req, fmt.Errorf("%w: point features require a numeric radius property", ErrInvalidIncident)
+		}
+		lat, lng := point.Lat(), point.Lng()
+		req.Latitude, req.Longitude, req.Radius = &lat, &lng, &radius
+	case models.GeometryPolygon, models.GeometryMultiPolygon:
+		var geometry models.Geometry
+		if err := json.Unmarshal(feature.Geometry, &geometry); err != nil {
+			return req, fmt.Errorf("%w: %v", ErrInvalidIncident, err)
+		}
+		req.Geometry = &geometry
+	default:
+		return req, fmt.Errorf("%w: unsupported geometry type %q", ErrInvalidIncident, header.Type)
+	}
+
+	return req, nil
+}
+
+// featureID извлекает UUID инцидента из id объекта или properties.id; прочие идентификаторы
+// (например, числовой fid из QGIS) игнорируются
+func featureID(feature models.Feature) uuid.UUID {
+	for _, candidate := range []interface{}{feature.ID, feature.Properties["id"]} {
+		if s, ok := candidate.(string); ok {
+			if id, err := uuid.Parse(s); err == nil {
+				return id
+			}
+		}
+	}
+	return uuid.Nil
+}
+
+func stringProperty(properties map[string]interface{}, key string) string {
+	value, _ := properties[key].(string)
+	return value
+}
+
+func createToUpdate(req models.CreateIncidentRequest) models.UpdateIncidentRequest {
+	update := models.UpdateIncidentRequest{
+		Title:       &req.Title,
+		Description: &req.Description,
+		Severity:    &req.Severity,
+		Geometry:    req.Geometry,
+		StartsAt:    models.OptionalTime{Set: true, Value: req.StartsAt},
+		ExpiresAt:   models.OptionalTime{Set: true, Value: req.ExpiresAt},
+	}
+	if req.Status != "" {
+		update.Status = &req.Status
+	}
+	if req.Geometry == nil {
+		update.Latitude = req.Latitude
+		update.Longitude = req.Longitude
+		update.Radius = req.Radius
+	}
+	return update
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"geo_system_core/internal/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestFeatureToRequest(t *testing.T) {
+	id := uuid.New()
+	point := json.RawMessage(`{"type":"Point","coordinates":[37.61,55.75]}`)
+	square := json.RawMessage(`{"type":"Polygon","coordinates":[[[37.60,55.74],[37.62,55.74],[37.62,55.76],[37.60,55.76],[37.60,55.74]]]}`)
+	properties := func(extra map[string]interface{}) map[string]interface{} {
+		p := map[string]interface{}{"title": "Пожар", "severity": "high", "radius": 500.0}
+		for k, v := range extra {
+			p[k] = v
+		}
+		return p
+	}
+
+	tests := []struct {
+		name    string
+		feature models.Feature
+		valid   bool
+		id      uuid.UUID
+		polygon bool
+	}{
+		{name: "Точка с радиусом", feature: models.Feature{Type: "Feature", Geometry: point, Properties: properties(nil)}, valid: true},
+		{name: "Полигон", feature: models.Feature{Type: "Feature", Geometry: square, Properties: properties(nil)}, valid: true, polygon: true},
+		{name: "Заголовок из name", feature: models.Feature{Type: "Feature", Geometry: point, Properties: properties(map[string]interface{}{"title": "", "name": "Пожар"})}, valid: true},
+		{name: "UUID в id объекта", feature: models.Feature{Type: "Feature", ID: id.String(), Geometry: point, Properties: properties(nil)}, valid: true, id: id},
+		{name: "UUID в properties.id", feature: models.Feature{Type: "Feature", Geometry: point, Properties: properties(map[string]interface{}{"id": id.String()})}, valid: true, id: id},
+		{name: "Числовой id игнорируется", feature: models.Feature{Type: "Feature", ID: 7.0, Geometry: point, Properties: properties(nil)}, valid: true},
+		{name: "Не Feature", feature: models.Feature{Type: "FeatureCollection", Geometry: point, Properties: properties(nil)}, valid: false},
+		{name: "Без заголовка", feature: models.Feature{Type: "Feature", Geometry: point, Properties: properties(map[string]interface{}{"title": ""})}, valid: false},
+		{name: "Неизвестная важность", feature: models.Feature{Type: "Feature", Geometry: point, Properties: properties(map[string]interface{}{"severity": "extreme"})}, valid: false},
+		{name: "Неизвестный статус", feature: models.Feature{Type: "Feature", Geometry: point, Properties: properties(map[string]interface{}{"status": "closed"})}, valid: false},
+		{name: "Точка без радиуса", feature: models.Feature{Type: "Feature", Geometry: point, Properties: properties(map[string]interface{}{"radius": "500"})}, valid: false},
+		{name: "Неверная дата", feature: models.Feature{Type: "Feature", Geometry: point, Properties: properties(map[string]interface{}{"expires_at": "завтра"})}, valid: false},
+		{name: "Линия не поддерживается", feature: models.Feature{Type: "Feature", Geometry: json.RawMessage(`{"type":"LineString","coordinates":[[37.6,55.7],[37.7,55.8]]}`), Properties: properties(nil)}, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := featureToRequest(tt.feature)
+			if (err == nil) != tt.valid {
+				t.Fatalf("featureToRequest() error = %v, expected valid: %v", err, tt.valid)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrInvalidIncident) {
+					t.Errorf("featureToRequest() error = %v, expected ErrInvalidIncident", err)
+				}
+				return
+			}
+			if req.ID != tt.id {
+				t.Errorf("featureToRequest() id = %s, expected %s", req.ID, tt.id)
+			}
+			if (req.Geometry != nil) != tt.polygon {
+				t.Errorf("featureToRequest() geometry = %v, expected polygon: %v", req.Geometry, tt.polygon)
+			}
+			if !tt.polygon && (req.Latitude == nil || *req.Latitude != 55.75 || req.Radius == nil || *req.Radius != 500) {
+				t.Errorf("featureToRequest() circle = %v, %v, expected 55.75 and 500", req.Latitude, req.Radius)
+			}
+		})
+	}
+}
+
+func TestUpsertAction(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name      string
+		owner     string
+		isActive  bool
+		lookupErr error
+		action    string
+		invalid   bool
+	}{
+		{name: "Инцидента нет", lookupErr: fmt.Errorf("incident not found"), action: "created"},
+		{name: "Инцидент арендатора", owner: "north", isActive: true, action: "updated"},
+		{name: "Удаленный инцидент", owner: "north", isActive: false, invalid: true},
+		{name: "Инцидент другого арендатора", owner: "south", isActive: true, invalid: true},
+		{name: "Ошибка БД", lookupErr: errors.New("failed to get incident owner: connection refused")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, err := upsertAction("north", id, tt.owner, tt.isActive, tt.lookupErr)
+			if action != tt.action {
+				t.Errorf("upsertAction() action = %q, expected %q", action, tt.action)
+			}
+			if tt.action != "" {
+				if err != nil {
+					t.Errorf("upsertAction() error = %v, expected nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("upsertAction() error = nil, expected error")
+			}
+			if errors.Is(err, ErrInvalidIncident) != tt.invalid {
+				t.Errorf("upsertAction() error = %v, expected ErrInvalidIncident: %v", err, tt.invalid)
+			}
+		})
+	}
+}
+
+func TestImportFeatures(t *testing.T) {
+	fc := models.FeatureCollection{Type: "FeatureCollection", Features: []models.Feature{
+		{Properties: map[string]interface{}{"title": "Новый"}},
+		{Properties: map[string]interface{}{"title": "Ошибка"}},
+		{Properties: map[string]interface{}{"title": "Существующий"}},
+	}}
+
+	response := importFeatures(fc, func(feature models.Feature) (*models.Incident, string, error) {
+		switch feature.Properties["title"] {
+		case "Новый":
+			return &models.Incident{ID: uuid.New()}, "created", nil
+		case "Существующий":
+			return &models.Incident{ID: uuid.New()}, "updated", nil
+		default:
+			return nil, "", fmt.Errorf("%w: property severity must be one of low, medium, high, critical", ErrInvalidIncident)
+		}
+	})
+
+	if response.Created != 1 || response.Updated != 1 || response.Failed != 1 {
+		t.Fatalf("importFeatures() = %d created, %d updated, %d failed, expected 1, 1, 1", response.Created, response.Updated, response.Failed)
+	}
+	if len(response.Results) != 3 {
+		t.Fatalf("importFeatures() results = %d, expected 3", len(response.Results))
+	}
+	for i, result := range response.Results {
+		if result.Index != i {
+			t.Errorf("importFeatures() result %d index = %d", i, result.Index)
+		}
+	}
+	if failed := response.Results[1]; failed.Error == "" || failed.ID != nil {
+		t.Errorf("importFeatures() failed result = %+v, expected error without id", failed)
+	}
+	if updated := response.Results[2]; updated.Action != "updated" || updated.ID == nil {
+		t.Errorf("importFeatures() updated result = %+v, expected action updated with id", updated)
+	}
+}
+
+func TestExportFeatures(t *testing.T) {
+	expires := time.Now().Add(time.Hour)
+	circle := models.Incident{ID: uuid.New(), Title: "Пожар", Severity: "high", Status: "active", Latitude: 55.75, Longitude: 37.61, Radius: 500, ExpiresAt: &expires}
+	polygon := models.Incident{ID: uuid.New(), Title: "Наводнение", Severity: "medium", Status: "active", Geometry: mustGeometry(t,
+		`{"type":"Polygon","coordinates":[[[37.60,55.74],[37.62,55.74],[37.62,55.76],[37.60,55.76],[37.60,55.74]]]}`)}
+
+	tests := []struct {
+		name              string
+		circlesAsPolygons bool
+		circleType        string
+	}{
+		{name: "Окружность точкой", circlesAsPolygons: false, circleType: "Point"},
+		{name: "Окружность полигоном", circlesAsPolygons: true, circleType: models.GeometryPolygon},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc, err := exportFeatures([]models.Incident{circle, polygon}, tt.circlesAsPolygons, 0)
+			if err != nil {
+				t.Fatalf("exportFeatures() error = %v", err)
+			}
+			if fc.Type != "FeatureCollection" || len(fc.Features) != 2 {
+				t.Fatalf("exportFeatures() = %s with %d features, expected FeatureCollection with 2", fc.Type, len(fc.Features))
+			}
+
+			types := make([]string, len(fc.Features))
+			for i, feature := range fc.Features {
+				var header struct {
+					Type string `json:"type"`
+				}
+				if err := json.Unmarshal(feature.Geometry, &header); err != nil {
+					t.Fatalf("exportFeatures() geometry %d: %v", i, err)
+				}
+				types[i] = header.Type
+			}
+			if types[0] != tt.circleType || types[1] != models.GeometryPolygon {
+				t.Errorf("exportFeatures() geometry types = %v, expected %s and %s", types, tt.circleType, models.GeometryPolygon)
+			}
+			if fc.Features[0].Properties["radius"] != 500.0 {
+				t.Errorf("exportFeatures() radius = %v, expected 500", fc.Features[0].Properties["radius"])
+			}
+			if _, ok := fc.Features[1].Properties["radius"]; ok {
+				t.Error("exportFeatures() polygon has radius property")
+			}
+
+			// Выгруженный объект после сериализации снова импортируется в тот же инцидент
+			data, err := json.Marshal(fc.Features[0])
+			if err != nil {
+				t.Fatalf("failed to marshal feature: %v", err)
+			}
+			var feature models.Feature
+			if err := json.Unmarshal(data, &feature); err != nil {
+				t.Fatalf("failed to unmarshal feature: %v", err)
+			}
+			req, err := featureToRequest(feature)
+			if err != nil {
+				t.Fatalf("featureToRequest() of exported feature error = %v", err)
+			}
+			if req.ID != circle.ID || req.Title != circle.Title || req.ExpiresAt == nil {
+				t.Errorf("featureToRequest() of exported feature = %+v, expected id, title and expires_at of the incident", req)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"fmt"
+	"geo_system_core/internal/models"
+	"math"
+)
+
+const earthRadiusMeters = 6371000
+
+// validateGeometry проверяет полигональную геометрию зоны: непустые замкнутые кольца
+// с корректными координатами, «дыры» внутри внешнего контура.
+func validateGeometry(g *models.Geometry) error {
+	if g.Type != models.GeometryPolygon && g.Type != models.GeometryMultiPolygon {
+		return fmt.Errorf("unsupported geometry type %q", g.Type)
+	}
+	if len(g.Polygons) == 0 {
+		return fmt.Errorf("geometry must contain at least one polygon")
+	}
+
+	for i, polygon := range g.Polygons {
+		if len(polygon) == 0 {
+			return fmt.Errorf("polygon %d has no rings", i)
+		}
+		for j, ring := range polygon {
+			if len(ring) < 4 {
+				return fmt.Errorf("polygon %d ring %d must have at least 4 positions", i, j)
+			}
+			if ring[0] != ring[len(ring)-1] {
+				return fmt.Errorf("polygon %d ring %d is not closed", i, j)
+			}
+			for _, p := range ring {
+				if err := validateCoordinates(p.Lat(), p.Lng()); err != nil {
+					return fmt.Errorf("polygon %d ring %d: %w", i, j, err)
+				}
+			}
+			if ringArea(ring) == 0 {
+				return fmt.Errorf("polygon %d ring %d has zero area", i, j)
+			}
+			// Дыра должна лежать внутри внешнего контура
+			if j > 0 && !pointInRing(polygon[0], ring[0].Lat(), ring[0].Lng()) {
+				return fmt.Errorf("polygon %d hole %d is outside of the exterior ring", i, j)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateCoordinates(lat, lng float64) error {
+	if lat < -90 || lat > 90 {
+		return fmt.Errorf("invalid latitude: must be between -90 and 90")
+	}
+	if lng < -180 || lng > 180 {
+		return fmt.Errorf("invalid longitude: must be between -180 and 180")
+	}
+	return nil
+}
+
+// geometryCircle возвращает центр габаритного прямоугольника геометрии и радиус
+// описанной вокруг него окружности в метрах. Используется для грубого отбора зон по расстоянию.
+func geometryCircle(g *models.Geometry) (lat, lng, radius float64) {
+	minLat, minLng, maxLat, maxLng := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, polygon := range g.Polygons {
+		for _, p := range polygon[0] {
+			minLat = math.Min(minLat, p.Lat())
+			maxLat = math.Max(maxLat, p.Lat())
+			minLng = math.Min(minLng, p.Lng())
+			maxLng = math.Max(maxLng, p.Lng())
+		}
+	}
+
+	lat = (minLat + maxLat) / 2
+	lng = (minLng + maxLng) / 2
+	for _, polygon := range g.Polygons {
+		for _, p := range polygon[0] {
+			radius = math.Max(radius, CalculateDistance(lat, lng, p.Lat(), p.Lng()))
+		}
+	}
+
+	return lat, lng, radius
+}
+
+// pointInGeometry проверяет попадание точки хотя бы в один полигон с учетом дыр
+func pointInGeometry(g *models.Geometry, lat, lng float64) bool {
+	for _, polygon := range g.Polygons {
+		if pointInPolygon(polygon, lat, lng) {
+			return true
+		}
+	}
+	return false
+}
+
+func pointInPolygon(polygon models.Polygon, lat, lng float64) bool {
+	if len(polygon) == 0 || !pointInRing(polygon[0], lat, lng) {
+		return false
+	}
+	for _, hole := range polygon[1:] {
+		if pointInRing(hole, lat, lng) {
+			return false
+		}
+	}
+	return true
+}
+
+// pointInRing — алгоритм трассировки луча в плоскости (долгота, широта).
+// Для зон размером в десятки километров искажение проекции несущественно.
+func pointInRing(ring models.Ring, lat, lng float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		yi, xi := ring[i].Lat(), ring[i].Lng()
+		yj, xj := ring[j].Lat(), ring[j].Lng()
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// ringArea — удвоенная площадь кольца в градусах (формула шнурования)
+func ringArea(ring models.Ring) float64 {
+	area := 0.0
+	for i := 0; i < len(ring)-1; i++ {
+		area += ring[i].Lng()*ring[i+1].Lat() - ring[i+1].Lng()*ring[i].Lat()
+	}
+	return math.Abs(area)
+}
+
+// incidentContains проверяет, находится ли точка внутри зоны инцидента
+func incidentContains(incident *models.Incident, lat, lng, distance float64) bool {
+	if incident.Geometry != nil {
+		return distance <= incident.Radius && pointInGeometry(incident.Geometry, lat, lng)
+	}
+	return distance <= incident.Radius
+}
+
+// circleToPolygon аппроксимирует окружность правильным многоугольником из segments вершин.
+// Вершины обходятся против часовой стрелки, как рекомендует RFC 7946 для внешнего контура.
+func circleToPolygon(lat, lng, radius float64, segments int) *models.Geometry {
+	ring := make(models.Ring, 0, segments+1)
+	for i := 0; i < segments; i++ {
+		bearing := -2 * math.Pi * float64(i) / float64(segments)
+		ring = append(ring, destinationPoint(lat, lng, radius, bearing))
+	}
+	ring = append(ring, ring[0])
+
+	return &models.Geometry{
+		Type:     models.GeometryPolygon,
+		Polygons: []models.Polygon{{ring}},
+	}
+}
+
+// destinationPoint возвращает точку на расстоянии distance метров от исходной по азимуту bearing (в радианах)
+func destinationPoint(lat, lng, distance, bearing float64) models.Position {
+	lat1 := lat * math.Pi / 180
+	lng1 := lng * math.Pi / 180
+	delta := distance / earthRadiusMeters
+
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(delta) + math.Cos(lat1)*math.Sin(delta)*math.Cos(bearing))
+	lng2 := lng1 + math.Atan2(math.Sin(bearing)*math.Sin(delta)*math.Cos(lat1), math.Cos(delta)-math.Sin(lat1)*math.Sin(lat2))
+
+	return models.Position{lng2 * 180 / math.Pi, lat2 * 180 / math.Pi}
+}
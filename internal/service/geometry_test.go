@@ -0,0 +1,102 @@
+package service
+
+import (
+	"encoding/json"
+	"geo_system_core/internal/models"
+	"testing"
+)
+
+func mustGeometry(t *testing.T, data string) *models.Geometry {
+	t.Helper()
+	var g models.Geometry
+	if err := json.Unmarshal([]byte(data), &g); err != nil {
+		t.Fatalf("failed to parse geometry: %v", err)
+	}
+	return &g
+}
+
+func TestPointInGeometry(t *testing.T) {
+	// Квадрат 1x1 градус с квадратной дырой в центре
+	polygonWithHole := mustGeometry(t, `{
+		"type": "Polygon",
+		"coordinates": [
+			[[37, 55], [38, 55], [38, 56], [37, 56], [37, 55]],
+			[[37.4, 55.4], [37.6, 55.4], [37.6, 55.6], [37.4, 55.6], [37.4, 55.4]]
+		]
+	}`)
+	multiPolygon := mustGeometry(t, `{
+		"type": "MultiPolygon",
+		"coordinates": [
+			[[[37, 55], [38, 55], [38, 56], [37, 56], [37, 55]]],
+			[[[40, 50], [41, 50], [41, 51], [40, 51], [40, 50]]]
+		]
+	}`)
+
+	tests := []struct {
+		name     string
+		geometry *models.Geometry
+		lat      float64
+		lng      float64
+		expected bool
+	}{
+		{"Внутри внешнего контура", polygonWithHole, 55.2, 37.2, true},
+		{"Внутри дыры", polygonWithHole, 55.5, 37.5, false},
+		{"Снаружи полигона", polygonWithHole, 54.9, 37.5, false},
+		{"Во втором полигоне мультиполигона", multiPolygon, 50.5, 40.5, true},
+		{"Между полигонами мультиполигона", multiPolygon, 53, 39, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pointInGeometry(tt.geometry, tt.lat, tt.lng); got != tt.expected {
+				t.Errorf("pointInGeometry() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidateGeometry(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{
+			name:    "Корректный полигон",
+			data:    `{"type": "Polygon", "coordinates": [[[37, 55], [38, 55], [38, 56], [37, 55]]]}`,
+			wantErr: false,
+		},
+		{
+			name:    "Незамкнутое кольцо",
+			data:    `{"type": "Polygon", "coordinates": [[[37, 55], [38, 55], [38, 56], [37, 56]]]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Слишком мало точек",
+			data:    `{"type": "Polygon", "coordinates": [[[37, 55], [38, 55], [37, 55]]]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Широта вне диапазона",
+			data:    `{"type": "Polygon", "coordinates": [[[37, 95], [38, 55], [38, 56], [37, 95]]]}`,
+			wantErr: true,
+		},
+		{
+			name: "Дыра вне внешнего контура",
+			data: `{"type": "Polygon", "coordinates": [
+				[[37, 55], [38, 55], [38, 56], [37, 56], [37, 55]],
+				[[39, 55], [39.5, 55], [39.5, 55.5], [39, 55]]
+			]}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateGeometry(mustGeometry(t, tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateGeometry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"geo_system_core/internal/models"
+	"geo_system_core/internal/repository/redis"
+	"sync"
+	"time"
+)
+
+const (
+	readinessTimeout       = 2 * time.Second
+	workerHeartbeatTimeout = time.Minute // воркер без прохода цикла дольше считается зависшим
+)
+
+// Pinger проверяет доступность хранилища; ему соответствует *pgxpool.Pool
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type HealthService struct {
+	db        Pinger
+	queueRepo *redis.QueueRepository
+	webhook   *WebhookService
+}
+
+func NewHealthService(db Pinger, queueRepo *redis.QueueRepository, webhook *WebhookService) *HealthService {
+	return &HealthService{db: db, queueRepo: queueRepo, webhook: webhook}
+}
+
+// Readiness параллельно проверяет Postgres, Redis и воркер вебхуков.
+// ready == false, если недоступна БД или Redis: без них экземпляр не может обслуживать запросы.
+func (s *HealthService) Readiness(ctx context.Context) (response *models.ReadinessResponse, ready bool) {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		database models.DependencyStatus
+		cache    models.DependencyStatus
+		depth    *models.WebhookQueueDepth
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		database = check(ctx, s.db.Ping)
+	}()
+	go func() {
+		defer wg.Done()
+		cache = check(ctx, func(ctx context.Context) error {
+			queued, processing, err := s.queueRepo.WebhookQueueLength(ctx)
+			if err == nil {
+				depth = &models.WebhookQueueDepth{Queued: queued, Processing: processing}
+			}
+			return err
+		})
+	}()
+	wg.Wait()
+
+	worker := models.DependencyStatus{Status: models.StatusUp}
+	lastSeen := s.webhook.LastHeartbeat()
+	if lastSeen.IsZero() || time.Since(lastSeen) > workerHeartbeatTimeout {
+		worker.Status = models.StatusDown
+	}
+	if !lastSeen.IsZero() {
+		worker.LastSeen = &lastSeen
+	}
+
+	response = &models.ReadinessResponse{
+		Status:  "ok",
+		Service: "geo_system_core",
+		Checks: map[string]models.DependencyStatus{
+			"postgres":       database,
+			"redis":          cache,
+			"webhook_worker": worker,
+		},
+		WebhookQueue: depth,
+	}
+
+	ready = database.Status == models.StatusUp && cache.Status == models.StatusUp
+	if !ready {
+		response.Status = "unavailable"
+	} else if worker.Status != models.StatusUp {
+		response.Status = "degraded"
+	}
+
+	return response, ready
+}
+
+func check(ctx context.Context, ping func(ctx context.Context) error) models.DependencyStatus {
+	start := time.Now()
+	err := ping(ctx)
+	status := models.DependencyStatus{
+		Status:    models.StatusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		status.Status = models.StatusDown
+		status.Error = err.Error()
+	}
+	return status
+}
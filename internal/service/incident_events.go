@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"geo_system_core/internal/auth"
+	"geo_system_core/internal/models"
+	"geo_system_core/internal/repository/redis"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidEventID — Last-Event-ID не является идентификатором записи потока
+var ErrInvalidEventID = errors.New("invalid Last-Event-ID")
+
+const (
+	// eventSubscriberBuffer — сколько событий может ждать отправки клиенту. Если клиент не успевает
+	// читать, подписка закрывается, и он переподключается с Last-Event-ID.
+	eventSubscriberBuffer = 64
+	// eventReplayLimit — сколько пропущенных событий отдается при возобновлении; если пропущено больше,
+	// клиент получает incidents.reset
+	eventReplayLimit = 1000
+)
+
+// IncidentEvents публикует изменения инцидентов в Redis и раздает события, полученные
+// через pub/sub от всех реплик, подписчикам SSE этой реплики
+type IncidentEvents struct {
+	repo      *redis.EventRepository
+	heartbeat time.Duration
+	closing   <-chan struct{}
+
+	mu          sync.Mutex
+	subscribers map[*EventSubscription]struct{}
+}
+
+// NewIncidentEvents создает рассылку; потоки клиентов завершаются при закрытии closing (остановке сервиса)
+func NewIncidentEvents(repo *redis.EventRepository, heartbeat time.Duration, closing <-chan struct{}) *IncidentEvents {
+	return &IncidentEvents{
+		repo:        repo,
+		heartbeat:   heartbeat,
+		closing:     closing,
+		subscribers: make(map[*EventSubscription]struct{}),
+	}
+}
+
+// EventSubscription — подписка одного клиента на события арендатора. Backlog содержит события,
+// пропущенные после Last-Event-ID; они отправляются до событий из Events. Если пропущено больше
+// eventReplayLimit событий или событие Last-Event-ID уже вытеснено из потока, Backlog пуст,
+// а ResetID содержит идентификатор последнего события потока:
+// клиент получает incidents.reset с этим идентификатором и заново загружает инциденты.
+type EventSubscription struct {
+	Backlog []models.IncidentEvent
+	ResetID string
+
+	events   *IncidentEvents
+	tenantID string
+	severity []string
+	bbox     *models.BoundingBox
+	ch       chan models.IncidentEvent
+	lastID   string
+}
+
+// Events возвращает канал новых событий; он закрывается, если клиент не успевает их читать
+func (s *EventSubscription) Events() <-chan models.IncidentEvent {
+	return s.ch
+}
+
+// Done закрывается при остановке сервиса; клиент переподключится к другой реплике
+func (s *EventSubscription) Done() <-chan struct{} {
+	return s.events.closing
+}
+
+// Heartbeat — период комментариев, которые не дают прокси закрыть простаивающее соединение
+func (s *EventSubscription) Heartbeat() time.Duration {
+	return s.events.heartbeat
+}
+
+// Seen сообщает, что событие уже отправлено клиенту из Backlog или получено им до переподключения
+func (s *EventSubscription) Seen(event models.IncidentEvent) bool {
+	return s.lastID != "" && !streamIDAfter(event.ID, s.lastID)
+}
+
+func (s *EventSubscription) Close() {
+	s.events.mu.Lock()
+	defer s.events.mu.Unlock()
+
+	if _, ok := s.events.subscribers[s]; ok {
+		delete(s.events.subscribers, s)
+		close(s.ch)
+	}
+}
+
+func (s *EventSubscription) matches(event models.IncidentEvent) bool {
+	return event.TenantID == s.tenantID && eventMatches(s.severity, s.bbox, &event.Incident)
+}
+
+// Subscribe подписывает клиента на события арендатора из контекста запроса. Подписка регистрируется
+// до чтения пропущенных событий, поэтому событие, записанное между ними, не теряется.
+func (e *IncidentEvents) Subscribe(ctx context.Context, filter models.IncidentEventFilter, lastEventID string) (*EventSubscription, error) {
+	severity, err := splitSeverities(filter.Severity)
+	if err != nil {
+		return nil, err
+	}
+	bbox, err := filterBoundingBox(filter.MinLat, filter.MinLng, filter.MaxLat, filter.MaxLng)
+	if err != nil {
+		return nil, err
+	}
+	if lastEventID != "" && !validStreamID(lastEventID) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidEventID, lastEventID)
+	}
+
+	sub := &EventSubscription{
+		events:   e,
+		tenantID: auth.TenantFromContext(ctx),
+		severity: severity,
+		bbox:     bbox,
+		ch:       make(chan models.IncidentEvent, eventSubscriberBuffer),
+		lastID:   lastEventID,
+	}
+	e.mu.Lock()
+	e.subscribers[sub] = struct{}{}
+	e.mu.Unlock()
+
+	if lastEventID == "" {
+		return sub, nil
+	}
+
+	missed, err := e.repo.Since(ctx, sub.tenantID, lastEventID, eventReplayLimit+1)
+	if err != nil {
+		sub.Close()
+		return nil, err
+	}
+	trimmed, err := e.trimmed(ctx, sub.tenantID, lastEventID)
+	if err != nil {
+		sub.Close()
+		return nil, err
+	}
+	if trimmed || len(missed) > eventReplayLimit {
+		// Часть событий не будет отправлена: вместо усеченного Backlog клиент перечитывает инциденты.
+		// События, записанные до lastID, уже есть в перечитанном списке.
+		lastID, err := e.repo.LastID(ctx, sub.tenantID)
+		if err != nil {
+			sub.Close()
+			return nil, err
+		}
+		sub.ResetID = lastID
+		sub.lastID = lastID
+		return sub, nil
+	}
+	for _, event := range missed {
+		if sub.matches(event) {
+			sub.Backlog = append(sub.Backlog, event)
+		}
+		sub.lastID = event.ID
+	}
+
+	return sub, nil
+}
+
+// trimmed сообщает, что событие lastEventID уже вытеснено из потока арендатора (EVENTS_STREAM_LENGTH)
+// и события после него могли быть потеряны. Поток читается после Since: вытеснение между чтениями
+// приводит к лишнему сбросу, а не к потере событий.
+func (e *IncidentEvents) trimmed(ctx context.Context, tenantID, lastEventID string) (bool, error) {
+	firstID, err := e.repo.FirstID(ctx, tenantID)
+	if err != nil {
+		return false, err
+	}
+	return firstID != "" && streamIDAfter(firstID, lastEventID), nil
+}
+
+// Publish записывает событие об инциденте; ошибка Redis не отменяет уже выполненное изменение
+func (e *IncidentEvents) Publish(ctx context.Context, eventType string, incident models.Incident) {
+	event := models.IncidentEvent{
+		Event:     eventType,
+		TenantID:  incident.TenantID,
+		Incident:  incident,
+		Timestamp: time.Now(),
+	}
+	if err := e.repo.Publish(ctx, &event); err != nil {
+		log.Printf("incident events: %s %s: %v", eventType, incident.ID, err)
+	}
+}
+
+// Start получает события всех реплик и раздает их подписчикам, переподключаясь при ошибках
+func (e *IncidentEvents) Start(ctx context.Context) {
+	for {
+		err := e.repo.Subscribe(ctx, e.dispatch)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("incident events: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (e *IncidentEvents) dispatch(event models.IncidentEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for sub := range e.subscribers {
+		if !sub.matches(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			// Клиент не успевает читать: закрываем подписку, чтобы не копить события в памяти
+			delete(e.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// eventMatches проверяет фильтры потока: важность из списка и центр зоны внутри прямоугольника
+func eventMatches(severity []string, bbox *models.BoundingBox, incident *models.Incident) bool {
+	if len(severity) > 0 && !slices.Contains(severity, incident.Severity) {
+		return false
+	}
+	if bbox != nil && !bbox.Contains(incident.Latitude, incident.Longitude) {
+		return false
+	}
+	return true
+}
+
+// parseStreamID разбирает идентификатор записи потока Redis вида <миллисекунды>-<номер>
+func parseStreamID(id string) (ms, seq uint64, ok bool) {
+	msPart, seqPart, found := strings.Cut(id, "-")
+	if !found {
+		return 0, 0, false
+	}
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	seq, err = strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return ms, seq, true
+}
+
+func validStreamID(id string) bool {
+	_, _, ok := parseStreamID(id)
+	return ok
+}
+
+// streamIDAfter сообщает, что запись a добавлена в поток позже записи b
+func streamIDAfter(a, b string) bool {
+	aMs, aSeq, _ := parseStreamID(a)
+	bMs, bSeq, _ := parseStreamID(b)
+	if aMs != bMs {
+		return aMs > bMs
+	}
+	return aSeq > bSeq
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"geo_system_core/internal/auth"
+	"geo_system_core/internal/models"
+	"geo_system_core/internal/redistest"
+	"geo_system_core/internal/repository/redis"
+	"testing"
+	"time"
+)
+
+func TestEventMatches(t *testing.T) {
+	incident := &models.Incident{Severity: "high", Latitude: 55.75, Longitude: 37.61}
+	moscow := &models.BoundingBox{MinLat: 55.5, MinLng: 37.3, MaxLat: 56.0, MaxLng: 37.9}
+	spb := &models.BoundingBox{MinLat: 59.8, MinLng: 30.1, MaxLat: 60.1, MaxLng: 30.6}
+
+	tests := []struct {
+		name     string
+		severity []string
+		bbox     *models.BoundingBox
+		expected bool
+	}{
+		{name: "Без фильтров", expected: true},
+		{name: "Важность из списка", severity: []string{"high", "critical"}, expected: true},
+		{name: "Важность не из списка", severity: []string{"critical"}, expected: false},
+		{name: "Центр внутри прямоугольника", bbox: moscow, expected: true},
+		{name: "Центр вне прямоугольника", bbox: spb, expected: false},
+		{name: "Оба фильтра", severity: []string{"high"}, bbox: moscow, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := eventMatches(tt.severity, tt.bbox, incident); result != tt.expected {
+				t.Errorf("eventMatches() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStreamIDAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		expected bool
+	}{
+		{name: "Более позднее время", a: "1700000000001-0", b: "1700000000000-5", expected: true},
+		{name: "Та же миллисекунда, больший номер", a: "1700000000000-10", b: "1700000000000-9", expected: true},
+		{name: "Та же запись", a: "1700000000000-1", b: "1700000000000-1", expected: false},
+		{name: "Более ранняя запись", a: "1699999999999-7", b: "1700000000000-0", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := streamIDAfter(tt.a, tt.b); result != tt.expected {
+				t.Errorf("streamIDAfter(%s, %s) = %v, expected %v", tt.a, tt.b, result, tt.expected)
+			}
+		})
+	}
+
+	for _, id := range []string{"", "123", "abc-1", "1-x", "-1"} {
+		if validStreamID(id) {
+			t.Errorf("validStreamID(%q) = true, expected false", id)
+		}
+	}
+}
+
+func TestSubscribeReplay(t *testing.T) {
+	tests := []struct {
+		name      string
+		streamLen int64
+		missed    int
+		backlog   int
+		reset     bool
+	}{
+		{name: "Нет пропущенных событий", streamLen: 100000, missed: 0, backlog: 0},
+		{name: "Пропущенные события отдаются", streamLen: 100000, missed: 3, backlog: 3},
+		{name: "Ровно предел возобновления", streamLen: 100000, missed: eventReplayLimit, backlog: eventReplayLimit},
+		{name: "Больше предела — сброс", streamLen: 100000, missed: eventReplayLimit + 1, reset: true},
+		{name: "Событие клиента еще в потоке", streamLen: 4, missed: 3, backlog: 3},
+		{name: "Событие клиента вытеснено из потока — сброс", streamLen: 2, missed: 3, reset: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := auth.WithTenant(context.Background(), "north")
+			repo := redis.NewEventRepository(redistest.NewServer(t).NewClient(t), tt.streamLen)
+			events := NewIncidentEvents(repo, time.Minute, make(chan struct{}))
+
+			publish := func() string {
+				event := models.IncidentEvent{Event: models.EventIncidentUpdated, TenantID: "north", Incident: models.Incident{Severity: "high"}}
+				if err := repo.Publish(ctx, &event); err != nil {
+					t.Fatalf("Publish() error = %v", err)
+				}
+				return event.ID
+			}
+			lastSeen := publish()
+			var lastID string
+			for i := 0; i < tt.missed; i++ {
+				lastID = publish()
+			}
+
+			sub, err := events.Subscribe(ctx, models.IncidentEventFilter{}, lastSeen)
+			if err != nil {
+				t.Fatalf("Subscribe() error = %v", err)
+			}
+			defer sub.Close()
+
+			if len(sub.Backlog) != tt.backlog {
+				t.Errorf("Subscribe() backlog = %d events, expected %d", len(sub.Backlog), tt.backlog)
+			}
+			if (sub.ResetID != "") != tt.reset {
+				t.Fatalf("Subscribe() reset id = %q, expected reset: %v", sub.ResetID, tt.reset)
+			}
+			if tt.reset && sub.ResetID != lastID {
+				t.Errorf("Subscribe() reset id = %s, expected last event %s", sub.ResetID, lastID)
+			}
+			if tt.backlog > 0 && sub.Backlog[tt.backlog-1].ID != lastID {
+				t.Errorf("Subscribe() last backlog event = %s, expected %s", sub.Backlog[tt.backlog-1].ID, lastID)
+			}
+			if tt.missed > 0 && !sub.Seen(models.IncidentEvent{ID: lastID}) {
+				t.Errorf("Seen(%s) = false, expected the replayed event to be skipped in the live stream", lastID)
+			}
+		})
+	}
+}
@@ -0,0 +1,433 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"geo_system_core/internal/metrics"
+	"geo_system_core/internal/models"
+	"geo_system_core/internal/repository/postgres"
+	"geo_system_core/internal/repository/redis"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	indexCellSize      = 0.1 // размер ячейки сетки в градусах (~11 км по широте)
+	indexMaxEntryCells = 4096
+	metersPerDegree    = 111320.0
+)
+
+type indexCell struct {
+	lat, lng int
+}
+
+type indexEntry struct {
+	incident models.Incident
+	minLat   float64
+	minLng   float64
+	maxLat   float64
+	maxLng   float64
+	wide     bool // зона покрывает слишком много ячеек и проверяется при каждом запросе
+}
+
+// Источники снимка индекса; им соответствуют *postgres.IncidentRepository и *redis.QueueRepository
+type (
+	unexpiredIncidentsLoader interface {
+		GetUnexpiredIncidents(ctx context.Context) ([]models.Incident, error)
+	}
+	incidentsCache interface {
+		ActiveIncidentsCacheVersion(ctx context.Context) (int64, error)
+		GetCachedActiveIncidents(ctx context.Context) ([]models.Incident, error)
+		CacheActiveIncidents(ctx context.Context, incidents []models.Incident, ttl time.Duration, version int64) (bool, error)
+		InvalidateCachedActiveIncidents(ctx context.Context) error
+	}
+)
+
+// indexChange — изменение, сделанное во время незавершенного Load; incident == nil — инцидент удален
+type indexChange struct {
+	seq      uint64
+	incident *models.Incident
+}
+
+// IncidentIndex — сетка по широте и долготе с незавершенными инцидентами всех арендаторов в памяти процесса.
+// Каждая зона регистрируется во всех ячейках, которые пересекает ее описанная окружность,
+// поэтому проверка координат просматривает только несколько соседних ячеек и не обращается к Postgres.
+// Общий снимок инцидентов кэшируется в Redis, чтобы реплики не нагружали БД при обновлении индекса.
+type IncidentIndex struct {
+	incidentRepo unexpiredIncidentsLoader
+	queueRepo    incidentsCache
+	cacheTTL     time.Duration
+
+	mu      sync.RWMutex
+	loaded  bool
+	entries map[uuid.UUID]*indexEntry
+	cells   map[indexCell]map[uuid.UUID]*indexEntry
+	wide    map[uuid.UUID]*indexEntry
+
+	// Upsert и Remove во время загрузки снимка запоминаются и применяются поверх него,
+	// иначе снимок, прочитанный до изменения, затер бы его
+	loads   int
+	seq     uint64
+	changes map[uuid.UUID]indexChange
+}
+
+func NewIncidentIndex(
+	incidentRepo *postgres.IncidentRepository,
+	queueRepo *redis.QueueRepository,
+	cacheTTL time.Duration,
+) *IncidentIndex {
+	return &IncidentIndex{
+		incidentRepo: incidentRepo,
+		queueRepo:    queueRepo,
+		cacheTTL:     cacheTTL,
+		entries:      make(map[uuid.UUID]*indexEntry),
+		cells:        make(map[indexCell]map[uuid.UUID]*indexEntry),
+		wide:         make(map[uuid.UUID]*indexEntry),
+		changes:      make(map[uuid.UUID]indexChange),
+	}
+}
+
+// Load перестраивает индекс из общего кэша в Redis, а при его отсутствии — из Postgres
+func (idx *IncidentIndex) Load(ctx context.Context) error {
+	idx.mu.Lock()
+	idx.loads++
+	since := idx.seq
+	idx.mu.Unlock()
+
+	incidents, err := idx.snapshot(ctx)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	defer func() {
+		idx.loads--
+		if idx.loads == 0 {
+			idx.changes = make(map[uuid.UUID]indexChange)
+		}
+	}()
+	if err != nil {
+		return err
+	}
+
+	idx.entries = make(map[uuid.UUID]*indexEntry, len(incidents))
+	idx.cells = make(map[indexCell]map[uuid.UUID]*indexEntry)
+	idx.wide = make(map[uuid.UUID]*indexEntry)
+	for _, incident := range incidents {
+		// Снимок мог записать экземпляр, не знающий об арендаторах
+		if incident.TenantID == "" {
+			incident.TenantID = models.DefaultTenant
+		}
+		idx.insert(incident)
+	}
+	for id, change := range idx.changes {
+		if change.seq <= since {
+			continue // изменение сделано до начала загрузки и уже есть в снимке
+		}
+		idx.remove(id)
+		if change.incident != nil {
+			idx.insert(*change.incident)
+		}
+	}
+	idx.loaded = true
+	idx.updateMetrics(time.Now())
+
+	return nil
+}
+
+// snapshot читает снимок из кэша или из Postgres. Снимок из Postgres кэшируется, только если
+// за время чтения ни одна реплика не сбросила кэш, иначе он может не содержать ее изменений.
+func (idx *IncidentIndex) snapshot(ctx context.Context) ([]models.Incident, error) {
+	incidents, err := idx.queueRepo.GetCachedActiveIncidents(ctx)
+	if err == nil && incidents != nil {
+		return incidents, nil
+	}
+
+	version, versionErr := idx.queueRepo.ActiveIncidentsCacheVersion(ctx)
+	incidents, err = idx.incidentRepo.GetUnexpiredIncidents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load incident index: %w", err)
+	}
+	if versionErr == nil {
+		if _, err := idx.queueRepo.CacheActiveIncidents(ctx, incidents, idx.cacheTTL, version); err != nil {
+			log.Printf("incident index: %v", err)
+		}
+	}
+
+	return incidents, nil
+}
+
+// StartRefresher периодически перечитывает индекс, чтобы подхватить изменения других реплик
+func (idx *IncidentIndex) StartRefresher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := idx.Load(ctx); err != nil {
+				log.Printf("incident index: %v", err)
+			}
+		}
+	}
+}
+
+// Upsert добавляет или заменяет инцидент после изменения через IncidentService.
+// Неактивные и завершенные инциденты удаляются из индекса.
+func (idx *IncidentIndex) Upsert(ctx context.Context, incident models.Incident) {
+	idx.mu.Lock()
+	idx.remove(incident.ID)
+	if incident.IsActive && incident.Status == "active" {
+		idx.insert(incident)
+		idx.record(incident.ID, &incident)
+	} else {
+		idx.record(incident.ID, nil)
+	}
+	idx.updateMetrics(time.Now())
+	idx.mu.Unlock()
+
+	idx.invalidateCache(ctx)
+}
+
+func (idx *IncidentIndex) Remove(ctx context.Context, id uuid.UUID) {
+	idx.mu.Lock()
+	idx.remove(id)
+	idx.record(id, nil)
+	idx.updateMetrics(time.Now())
+	idx.mu.Unlock()
+
+	idx.invalidateCache(ctx)
+}
+
+// Nearby возвращает инциденты арендатора, действующие в момент now, граница описанной окружности которых
+// находится не дальше maxDistance метров от точки, в порядке удаления центра.
+// ok == false, если индекс еще не загружен.
+func (idx *IncidentIndex) Nearby(tenantID string, lat, lng, maxDistance float64, now time.Time) (incidents []models.Incident, ok bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if !idx.loaded {
+		return nil, false
+	}
+
+	minLat, minLng, maxLat, maxLng := circleBounds(lat, lng, maxDistance)
+	candidates := idx.entries
+	if cells, wide := boundsCells(minLat, minLng, maxLat, maxLng); !wide {
+		candidates = make(map[uuid.UUID]*indexEntry, len(idx.wide))
+		for _, cell := range cells {
+			for id, entry := range idx.cells[cell] {
+				candidates[id] = entry
+			}
+		}
+		for id, entry := range idx.wide {
+			candidates[id] = entry
+		}
+	}
+
+	distances := make(map[uuid.UUID]float64)
+	for id, entry := range candidates {
+		if entry.incident.TenantID != tenantID {
+			continue
+		}
+		if entry.maxLat < minLat || entry.minLat > maxLat || !lngOverlap(entry.minLng, entry.maxLng, minLng, maxLng) {
+			continue
+		}
+		if !activeAt(&entry.incident, now) {
+			continue
+		}
+		distance := CalculateDistance(lat, lng, entry.incident.Latitude, entry.incident.Longitude)
+		if distance-entry.incident.Radius > maxDistance {
+			continue
+		}
+		distances[id] = distance
+		incidents = append(incidents, entry.incident)
+	}
+
+	sort.Slice(incidents, func(i, j int) bool {
+		return distances[incidents[i].ID] < distances[incidents[j].ID]
+	})
+
+	return incidents, true
+}
+
+// Active возвращает снимок всех инцидентов арендатора, действующих в момент now.
+// ok == false, если индекс еще не загружен.
+func (idx *IncidentIndex) Active(tenantID string, now time.Time) (incidents []models.Incident, ok bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if !idx.loaded {
+		return nil, false
+	}
+
+	for _, entry := range idx.entries {
+		if entry.incident.TenantID == tenantID && activeAt(&entry.incident, now) {
+			incidents = append(incidents, entry.incident)
+		}
+	}
+
+	return incidents, true
+}
+
+// record запоминает изменение для загрузок, которые идут в этот момент; вызывается под блокировкой на запись
+func (idx *IncidentIndex) record(id uuid.UUID, incident *models.Incident) {
+	if idx.loads == 0 {
+		return
+	}
+	idx.seq++
+	idx.changes[id] = indexChange{seq: idx.seq, incident: incident}
+}
+
+// insert и remove вызываются под блокировкой на запись
+func (idx *IncidentIndex) insert(incident models.Incident) {
+	entry := &indexEntry{incident: incident}
+	entry.minLat, entry.minLng, entry.maxLat, entry.maxLng = circleBounds(incident.Latitude, incident.Longitude, incident.Radius)
+	idx.entries[incident.ID] = entry
+
+	cells, wide := boundsCells(entry.minLat, entry.minLng, entry.maxLat, entry.maxLng)
+	if wide {
+		entry.wide = true
+		idx.wide[incident.ID] = entry
+		return
+	}
+	for _, cell := range cells {
+		if idx.cells[cell] == nil {
+			idx.cells[cell] = make(map[uuid.UUID]*indexEntry)
+		}
+		idx.cells[cell][incident.ID] = entry
+	}
+}
+
+func (idx *IncidentIndex) remove(id uuid.UUID) {
+	entry, ok := idx.entries[id]
+	if !ok {
+		return
+	}
+	delete(idx.entries, id)
+
+	if entry.wide {
+		delete(idx.wide, id)
+		return
+	}
+	cells, _ := boundsCells(entry.minLat, entry.minLng, entry.maxLat, entry.maxLng)
+	for _, cell := range cells {
+		delete(idx.cells[cell], id)
+		if len(idx.cells[cell]) == 0 {
+			delete(idx.cells, cell)
+		}
+	}
+}
+
+// updateMetrics пересчитывает число действующих инцидентов по важности; вызывается под блокировкой.
+// Начало действия запланированных инцидентов учитывается при следующем обновлении индекса.
+func (idx *IncidentIndex) updateMetrics(now time.Time) {
+	counts := make(map[string]int, len(severityRanks))
+	for severity := range severityRanks {
+		counts[severity] = 0
+	}
+	for _, entry := range idx.entries {
+		if activeAt(&entry.incident, now) {
+			counts[entry.incident.Severity]++
+		}
+	}
+	for severity, count := range counts {
+		metrics.ActiveIncidents.WithLabelValues(severity).Set(float64(count))
+	}
+}
+
+// invalidateCache сбрасывает общий кэш, чтобы реплики при следующем обновлении перечитали Postgres
+func (idx *IncidentIndex) invalidateCache(ctx context.Context) {
+	if err := idx.queueRepo.InvalidateCachedActiveIncidents(ctx); err != nil {
+		log.Printf("incident index: %v", err)
+	}
+}
+
+// activeAt проверяет, что инцидент активен и момент now попадает в его окно действия
+func activeAt(incident *models.Incident, now time.Time) bool {
+	if !incident.IsActive || incident.Status != "active" {
+		return false
+	}
+	if incident.StartsAt != nil && now.Before(*incident.StartsAt) {
+		return false
+	}
+	if incident.ExpiresAt != nil && !now.Before(*incident.ExpiresAt) {
+		return false
+	}
+	return true
+}
+
+// circleBounds возвращает габаритный прямоугольник окружности в градусах. Если окружность пересекает
+// антимеридиан, долготы выходят за ±180; lngRanges разбивает такой диапазон на два.
+func circleBounds(lat, lng, radius float64) (minLat, minLng, maxLat, maxLng float64) {
+	dLat := radius / metersPerDegree
+	minLat = math.Max(lat-dLat, -90)
+	maxLat = math.Min(lat+dLat, 90)
+
+	cos := math.Cos(lat * math.Pi / 180)
+	if cos < 0.01 || minLat == -90 || maxLat == 90 {
+		return minLat, -180, maxLat, 180
+	}
+	dLng := radius / (metersPerDegree * cos)
+	if dLng >= 180 {
+		return minLat, -180, maxLat, 180
+	}
+	return minLat, lng - dLng, maxLat, lng + dLng
+}
+
+// lngRanges приводит диапазон долгот из circleBounds к [-180, 180]: диапазон, пересекающий
+// антимеридиан, делится на части по обе его стороны
+func lngRanges(minLng, maxLng float64) [][2]float64 {
+	switch {
+	case maxLng-minLng >= 360:
+		return [][2]float64{{-180, 180}}
+	case minLng < -180:
+		return [][2]float64{{minLng + 360, 180}, {-180, maxLng}}
+	case maxLng > 180:
+		return [][2]float64{{minLng, 180}, {-180, maxLng - 360}}
+	}
+	return [][2]float64{{minLng, maxLng}}
+}
+
+// lngOverlap проверяет, пересекаются ли диапазоны долгот из circleBounds с учетом антимеридиана
+func lngOverlap(aMin, aMax, bMin, bMax float64) bool {
+	for _, a := range lngRanges(aMin, aMax) {
+		for _, b := range lngRanges(bMin, bMax) {
+			if a[0] <= b[1] && b[0] <= a[1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// boundsCells перечисляет ячейки сетки, пересекающие прямоугольник из circleBounds.
+// wide == true, если ячеек больше indexMaxEntryCells.
+func boundsCells(minLat, minLng, maxLat, maxLng float64) (cells []indexCell, wide bool) {
+	fromLat, toLat := cellCoord(minLat), cellCoord(maxLat)
+	ranges := lngRanges(minLng, maxLng)
+	count := 0
+	for _, r := range ranges {
+		count += (toLat - fromLat + 1) * (cellCoord(r[1]) - cellCoord(r[0]) + 1)
+	}
+	if count > indexMaxEntryCells {
+		return nil, true
+	}
+
+	for _, r := range ranges {
+		for lat := fromLat; lat <= toLat; lat++ {
+			for lng := cellCoord(r[0]); lng <= cellCoord(r[1]); lng++ {
+				cells = append(cells, indexCell{lat: lat, lng: lng})
+			}
+		}
+	}
+	return cells, false
+}
+
+func cellCoord(deg float64) int {
+	return int(math.Floor(deg / indexCellSize))
+}
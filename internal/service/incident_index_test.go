@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"geo_system_core/internal/models"
+	"geo_system_core/internal/redistest"
+	"geo_system_core/internal/repository/redis"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestIncidentIndexNearby(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+
+	newIncident := func(title string, lat, lng, radius float64) models.Incident {
+		return models.Incident{
+			ID: uuid.New(), TenantID: models.DefaultTenant, Title: title, Latitude: lat, Longitude: lng, Radius: radius,
+			Status: "active", IsActive: true,
+		}
+	}
+
+	near := newIncident("Рядом", 55.7560, 37.6175, 500)
+	far := newIncident("Санкт-Петербург", 59.9343, 30.3351, 500)
+	huge := newIncident("Огромная зона", 50, 40, 2_000_000)
+	scheduled := newIncident("Запланирована", 55.7558, 37.6173, 500)
+	scheduled.StartsAt = &later
+	expired := newIncident("Истекла", 55.7558, 37.6173, 500)
+	expired.ExpiresAt = &earlier
+	foreign := newIncident("Другой арендатор", 55.7558, 37.6173, 500)
+	foreign.TenantID = "north"
+
+	idx := NewIncidentIndex(nil, nil, 0)
+	for _, incident := range []models.Incident{far, huge, near, scheduled, expired, foreign} {
+		idx.insert(incident)
+	}
+
+	if _, ok := idx.Nearby(models.DefaultTenant, 55.7558, 37.6173, 10000, now); ok {
+		t.Fatalf("Nearby() on an unloaded index must report ok == false")
+	}
+	idx.loaded = true
+
+	incidents, ok := idx.Nearby(models.DefaultTenant, 55.7558, 37.6173, 10000, now)
+	if !ok {
+		t.Fatalf("Nearby() ok = false, expected true")
+	}
+
+	var titles []string
+	for _, incident := range incidents {
+		titles = append(titles, incident.Title)
+	}
+	expected := []string{"Рядом", "Огромная зона"}
+	if len(titles) != len(expected) {
+		t.Fatalf("Nearby() = %v, expected %v", titles, expected)
+	}
+	for i := range expected {
+		if titles[i] != expected[i] {
+			t.Errorf("Nearby()[%d] = %s, expected %s", i, titles[i], expected[i])
+		}
+	}
+
+	idx.remove(near.ID)
+	incidents, _ = idx.Nearby(models.DefaultTenant, 55.7558, 37.6173, 10000, now)
+	if len(incidents) != 1 || incidents[0].ID != huge.ID {
+		t.Errorf("Nearby() after remove returned %d incidents, expected only the huge zone", len(incidents))
+	}
+
+	incidents, _ = idx.Nearby("north", 55.7558, 37.6173, 10000, now)
+	if len(incidents) != 1 || incidents[0].ID != foreign.ID {
+		t.Errorf("Nearby() for another tenant returned %d incidents, expected only its own zone", len(incidents))
+	}
+	active, _ := idx.Active("north", now)
+	if len(active) != 1 || active[0].ID != foreign.ID {
+		t.Errorf("Active() for another tenant returned %d incidents, expected only its own zone", len(active))
+	}
+}
+
+func TestIncidentIndexAntimeridian(t *testing.T) {
+	east := models.Incident{ID: uuid.New(), TenantID: models.DefaultTenant, Title: "Восток", Latitude: 0, Longitude: 179.99, Radius: 5000, Status: "active", IsActive: true}
+	west := models.Incident{ID: uuid.New(), TenantID: models.DefaultTenant, Title: "Запад", Latitude: 0, Longitude: -179.99, Radius: 5000, Status: "active", IsActive: true}
+
+	tests := []struct {
+		name     string
+		incident models.Incident
+		lng      float64
+		found    bool
+	}{
+		{name: "Зона на востоке, точка на западе", incident: east, lng: -179.99, found: true},
+		{name: "Зона на западе, точка на востоке", incident: west, lng: 179.99, found: true},
+		{name: "Точка на той же стороне", incident: east, lng: 179.99, found: true},
+		{name: "Точка далеко за антимеридианом", incident: east, lng: -179, found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := NewIncidentIndex(nil, nil, 0)
+			idx.insert(tt.incident)
+			idx.loaded = true
+
+			incidents, _ := idx.Nearby(models.DefaultTenant, 0, tt.lng, 0, time.Now())
+			if (len(incidents) == 1) != tt.found {
+				t.Errorf("Nearby() at lng %g returned %d incidents, expected found: %v", tt.lng, len(incidents), tt.found)
+			}
+
+			idx.remove(tt.incident.ID)
+			if len(idx.cells) != 0 {
+				t.Errorf("remove() left %d cells, expected none", len(idx.cells))
+			}
+		})
+	}
+}
+
+// blockingLoader отдает снимок Postgres только после закрытия release и сообщает о начале чтения в started
+type blockingLoader struct {
+	incidents []models.Incident
+	started   chan struct{}
+	release   chan struct{}
+	calls     atomic.Int32
+}
+
+func (l *blockingLoader) GetUnexpiredIncidents(ctx context.Context) ([]models.Incident, error) {
+	l.calls.Add(1)
+	if l.started != nil {
+		l.started <- struct{}{}
+		<-l.release
+	}
+	return l.incidents, nil
+}
+
+func newLoadedIndex(t *testing.T, loader *blockingLoader) (*IncidentIndex, *redis.QueueRepository) {
+	cache := redis.NewQueueRepository(redistest.NewServer(t).NewClient(t))
+	idx := NewIncidentIndex(nil, cache, time.Minute)
+	idx.incidentRepo = loader
+	return idx, cache
+}
+
+func activeIDs(idx *IncidentIndex) map[uuid.UUID]bool {
+	active, _ := idx.Active(models.DefaultTenant, time.Now())
+	ids := make(map[uuid.UUID]bool, len(active))
+	for _, incident := range active {
+		ids[incident.ID] = true
+	}
+	return ids
+}
+
+func TestIncidentIndexLoadConcurrentUpsert(t *testing.T) {
+	ctx := context.Background()
+	incident := func() models.Incident {
+		return models.Incident{ID: uuid.New(), TenantID: models.DefaultTenant, Latitude: 55.75, Longitude: 37.61, Radius: 500, Status: "active", IsActive: true}
+	}
+	removed, kept, created := incident(), incident(), incident()
+
+	// Снимок прочитан до того, как removed удален, а created создан
+	loader := &blockingLoader{incidents: []models.Incident{removed, kept}, started: make(chan struct{}), release: make(chan struct{})}
+	idx, _ := newLoadedIndex(t, loader)
+
+	done := make(chan error)
+	go func() { done <- idx.Load(ctx) }()
+	<-loader.started
+	idx.Upsert(ctx, created)
+	idx.Remove(ctx, removed.ID)
+	close(loader.release)
+	if err := <-done; err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	ids := activeIDs(idx)
+	if len(ids) != 2 || !ids[kept.ID] || !ids[created.ID] {
+		t.Errorf("Active() after Load = %v, expected %s and %s", ids, kept.ID, created.ID)
+	}
+	if len(idx.changes) != 0 {
+		t.Errorf("changes after Load = %d, expected none", len(idx.changes))
+	}
+
+	// Изменения после загрузки не переживают следующую: снимок уже их содержит
+	loader.started = nil
+	loader.incidents = []models.Incident{kept}
+	if err := idx.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if ids := activeIDs(idx); len(ids) != 1 || !ids[kept.ID] {
+		t.Errorf("Active() after second Load = %v, expected only %s", ids, kept.ID)
+	}
+}
+
+func TestIncidentIndexLoadCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Пустой снимок кэшируется", func(t *testing.T) {
+		loader := &blockingLoader{}
+		idx, _ := newLoadedIndex(t, loader)
+		for i := 0; i < 2; i++ {
+			if err := idx.Load(ctx); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+		}
+		if calls := loader.calls.Load(); calls != 1 {
+			t.Errorf("GetUnexpiredIncidents() called %d times, expected 1", calls)
+		}
+	})
+
+	t.Run("Сброшенный во время чтения снимок не кэшируется", func(t *testing.T) {
+		loader := &blockingLoader{started: make(chan struct{}), release: make(chan struct{})}
+		idx, cache := newLoadedIndex(t, loader)
+
+		done := make(chan error)
+		go func() { done <- idx.Load(ctx) }()
+		<-loader.started
+		// Другая реплика изменила инцидент и сбросила кэш
+		if err := cache.InvalidateCachedActiveIncidents(ctx); err != nil {
+			t.Fatalf("InvalidateCachedActiveIncidents() error = %v", err)
+		}
+		close(loader.release)
+		if err := <-done; err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+
+		cached, err := cache.GetCachedActiveIncidents(ctx)
+		if err != nil {
+			t.Fatalf("GetCachedActiveIncidents() error = %v", err)
+		}
+		if cached != nil {
+			t.Errorf("GetCachedActiveIncidents() = %v, expected no snapshot", cached)
+		}
+	})
+}
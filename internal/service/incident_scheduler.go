@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"geo_system_core/internal/models"
+	"geo_system_core/internal/repository/postgres"
+	"geo_system_core/internal/repository/redis"
+	"log"
+	"time"
+)
+
+// Зависимости планировщика; им соответствуют *postgres.IncidentRepository, *redis.QueueRepository
+// и *IncidentEvents
+type (
+	expiredResolver interface {
+		ResolveExpired(ctx context.Context, actor models.Actor) ([]models.Incident, error)
+	}
+	webhookEnqueuer interface {
+		EnqueueWebhook(ctx context.Context, payload models.WebhookPayload) error
+	}
+	incidentPublisher interface {
+		Publish(ctx context.Context, eventType string, incident models.Incident)
+	}
+)
+
+// IncidentScheduler периодически переводит инциденты с истекшим expires_at в статус resolved
+// и ставит в очередь вебхук о завершении их действия; подписчики потока получают incident.resolved
+type IncidentScheduler struct {
+	incidentRepo expiredResolver
+	queueRepo    webhookEnqueuer
+	events       incidentPublisher
+	interval     time.Duration
+}
+
+func NewIncidentScheduler(
+	incidentRepo *postgres.IncidentRepository,
+	queueRepo *redis.QueueRepository,
+	events *IncidentEvents,
+	interval time.Duration,
+) *IncidentScheduler {
+	return &IncidentScheduler{
+		incidentRepo: incidentRepo,
+		queueRepo:    queueRepo,
+		events:       events,
+		interval:     interval,
+	}
+}
+
+func (s *IncidentScheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.resolveExpired(ctx)
+		}
+	}
+}
+
+func (s *IncidentScheduler) resolveExpired(ctx context.Context) {
+	incidents, err := s.incidentRepo.ResolveExpired(ctx, models.Actor{Name: "scheduler"})
+	if err != nil {
+		log.Printf("incident scheduler: %v", err)
+		return
+	}
+
+	for i := range incidents {
+		incident := incidents[i]
+		payload := models.WebhookPayload{
+			Event:     models.EventIncidentExpired,
+			TenantID:  incident.TenantID,
+			Latitude:  incident.Latitude,
+			Longitude: incident.Longitude,
+			Timestamp: time.Now(),
+			Incident:  &incident,
+		}
+		if err := s.queueRepo.EnqueueWebhook(ctx, payload); err != nil {
+			log.Printf("incident scheduler: incident %s: %v", incident.ID, err)
+		}
+		s.events.Publish(ctx, models.EventIncidentResolved, incident)
+	}
+}
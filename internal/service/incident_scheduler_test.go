@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"geo_system_core/internal/models"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type fakeExpiredResolver struct {
+	mu        sync.Mutex
+	calls     int
+	incidents []models.Incident
+	err       error
+}
+
+func (f *fakeExpiredResolver) ResolveExpired(_ context.Context, actor models.Actor) ([]models.Incident, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if actor.Name != "scheduler" {
+		return nil, errors.New("unexpected actor " + actor.Name)
+	}
+	incidents := f.incidents
+	f.incidents = nil // повторный проход не находит уже завершенные инциденты
+	return incidents, f.err
+}
+
+func (f *fakeExpiredResolver) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+type fakeWebhookEnqueuer struct {
+	payloads []models.WebhookPayload
+	fail     uuid.UUID // инцидент, вебхук которого не удается поставить в очередь
+}
+
+func (f *fakeWebhookEnqueuer) EnqueueWebhook(_ context.Context, payload models.WebhookPayload) error {
+	if payload.Incident.ID == f.fail {
+		return errors.New("redis is unavailable")
+	}
+	f.payloads = append(f.payloads, payload)
+	return nil
+}
+
+type fakeIncidentPublisher struct {
+	events []string
+}
+
+func (f *fakeIncidentPublisher) Publish(_ context.Context, eventType string, incident models.Incident) {
+	f.events = append(f.events, eventType+" "+incident.ID.String())
+}
+
+func TestIncidentSchedulerResolveExpired(t *testing.T) {
+	first := models.Incident{ID: uuid.New(), TenantID: "north", Latitude: 55.75, Longitude: 37.61, Status: "resolved"}
+	second := models.Incident{ID: uuid.New(), TenantID: "south", Status: "resolved"}
+
+	resolver := &fakeExpiredResolver{incidents: []models.Incident{first, second}}
+	queue := &fakeWebhookEnqueuer{fail: second.ID}
+	publisher := &fakeIncidentPublisher{}
+	scheduler := &IncidentScheduler{incidentRepo: resolver, queueRepo: queue, events: publisher}
+
+	scheduler.resolveExpired(context.Background())
+
+	if len(queue.payloads) != 1 {
+		t.Fatalf("resolveExpired() enqueued %d webhooks, expected 1", len(queue.payloads))
+	}
+	payload := queue.payloads[0]
+	if payload.Event != models.EventIncidentExpired || payload.TenantID != "north" || payload.Incident.ID != first.ID || payload.Latitude != first.Latitude {
+		t.Errorf("resolveExpired() payload = %+v, expected incident.expired of the first incident", payload)
+	}
+	// Ошибка очереди не мешает публикации события в поток
+	expected := []string{models.EventIncidentResolved + " " + first.ID.String(), models.EventIncidentResolved + " " + second.ID.String()}
+	if len(publisher.events) != 2 || publisher.events[0] != expected[0] || publisher.events[1] != expected[1] {
+		t.Errorf("resolveExpired() published %v, expected %v", publisher.events, expected)
+	}
+
+	failing := &IncidentScheduler{incidentRepo: &fakeExpiredResolver{err: errors.New("database is unavailable")}, queueRepo: queue, events: publisher}
+	failing.resolveExpired(context.Background())
+	if len(queue.payloads) != 1 || len(publisher.events) != 2 {
+		t.Errorf("resolveExpired() with a database error enqueued or published events")
+	}
+}
+
+func TestIncidentSchedulerStart(t *testing.T) {
+	resolver := &fakeExpiredResolver{}
+	scheduler := &IncidentScheduler{incidentRepo: resolver, queueRepo: &fakeWebhookEnqueuer{}, events: &fakeIncidentPublisher{}, interval: 5 * time.Millisecond}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		scheduler.Start(ctx)
+		close(done)
+	}()
+
+	deadline := time.After(time.Second)
+	for resolver.Calls() < 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("Start() made %d passes in a second, expected at least 2", resolver.Calls())
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Start() did not return after the context was cancelled")
+	}
+}
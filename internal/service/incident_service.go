@@ -2,20 +2,50 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"geo_system_core/internal/auth"
 	"geo_system_core/internal/models"
 	"geo_system_core/internal/repository/postgres"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidIncident — ошибка валидации данных инцидента (геометрия, координаты, радиус, окно действия)
+	ErrInvalidIncident = errors.New("invalid incident")
+	// ErrVersionMismatch — инцидент изменен после чтения: версия не совпадает с If-Match
+	ErrVersionMismatch = errors.New("incident version does not match If-Match")
+	// ErrInvalidFilter — недопустимое значение или сочетание фильтров списка инцидентов
+	ErrInvalidFilter = errors.New("invalid incident filter")
 )
 
+// IncidentService работает с инцидентами арендатора из контекста запроса
 type IncidentService struct {
-	repo *postgres.IncidentRepository
+	repo   *postgres.IncidentRepository
+	index  *IncidentIndex
+	events *IncidentEvents
 }
 
-func NewIncidentService(repo *postgres.IncidentRepository) *IncidentService {
-	return &IncidentService{repo: repo}
+func NewIncidentService(repo *postgres.IncidentRepository, index *IncidentIndex, events *IncidentEvents) *IncidentService {
+	return &IncidentService{repo: repo, index: index, events: events}
 }
 
 func (s *IncidentService) Create(ctx context.Context, req models.CreateIncidentRequest) (*models.Incident, error) {
-	return s.repo.Create(ctx, req)
+	if err := prepareCreate(&req); err != nil {
+		return nil, err
+	}
+
+	incident, err := s.repo.Create(ctx, auth.TenantFromContext(ctx), req, actorFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	s.index.Upsert(ctx, *incident)
+	s.events.Publish(ctx, models.EventIncidentCreated, *incident)
+
+	return incident, nil
 }
 
 func (s *IncidentService) GetByID(ctx context.Context, id string) (*models.Incident, error) {
@@ -23,10 +53,24 @@ func (s *IncidentService) GetByID(ctx context.Context, id string) (*models.Incid
 	if err != nil {
 		return nil, err
 	}
-	return s.repo.GetByID(ctx, uuid)
+	return s.repo.GetByID(ctx, auth.TenantFromContext(ctx), uuid)
 }
 
-func (s *IncidentService) List(ctx context.Context, page, limit int) ([]models.Incident, int, error) {
+// List возвращает страницу инцидентов по номеру или по курсору и курсор следующей страницы.
+// Курсор выдается и принимается только при сортировке по времени создания.
+func (s *IncidentService) List(ctx context.Context, filter models.IncidentFilter, cursor string, page, limit int) ([]models.Incident, int, string, error) {
+	if err := normalizeFilter(&filter); err != nil {
+		return nil, 0, "", err
+	}
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	byCreated := filter.Sort == "" || filter.Sort == "created_at" || filter.Sort == "-created_at"
+	if after != nil && !byCreated {
+		return nil, 0, "", fmt.Errorf("%w: cursor requires sorting by created_at", ErrInvalidCursor)
+	}
+
 	if page < 1 {
 		page = 1
 	}
@@ -36,25 +80,306 @@ func (s *IncidentService) List(ctx context.Context, page, limit int) ([]models.I
 	if limit > 100 {
 		limit = 100
 	}
-	return s.repo.List(ctx, page, limit)
+
+	// Лишняя запись показывает, есть ли следующая страница
+	incidents, total, err := s.repo.List(ctx, auth.TenantFromContext(ctx), filter, after, (page-1)*limit, limit+1)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	next := ""
+	if len(incidents) > limit {
+		incidents = incidents[:limit]
+		if byCreated {
+			last := incidents[limit-1]
+			next = encodeCursor(models.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		}
+	}
+
+	return incidents, total, next, nil
 }
 
-func (s *IncidentService) Update(ctx context.Context, id string, req models.UpdateIncidentRequest) (*models.Incident, error) {
+// Update изменяет инцидент атомарно: чтение, проверка версии и запись выполняются в одной транзакции.
+// ifMatch — допустимые версии из If-Match; nil — без проверки.
+func (s *IncidentService) Update(ctx context.Context, id string, req models.UpdateIncidentRequest, ifMatch []int) (*models.Incident, error) {
 	uuid, err := parseUUID(id)
 	if err != nil {
 		return nil, err
 	}
-	return s.repo.Update(ctx, uuid, req)
+
+	var previousStatus string
+	updated, err := s.repo.Update(ctx, auth.TenantFromContext(ctx), uuid, actorFromContext(ctx), func(incident *models.Incident) error {
+		if err := checkVersion(incident, ifMatch); err != nil {
+			return err
+		}
+		previousStatus = incident.Status
+		return applyUpdate(incident, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.index.Upsert(ctx, *updated)
+
+	event := models.EventIncidentUpdated
+	if updated.Status == "resolved" && previousStatus != "resolved" {
+		event = models.EventIncidentResolved
+	}
+	s.events.Publish(ctx, event, *updated)
+
+	return updated, nil
 }
 
-func (s *IncidentService) Delete(ctx context.Context, id string) error {
+// Delete деактивирует инцидент; ifMatch — допустимые версии из If-Match, nil — без проверки
+func (s *IncidentService) Delete(ctx context.Context, id string, ifMatch []int) error {
 	uuid, err := parseUUID(id)
 	if err != nil {
 		return err
 	}
-	return s.repo.Delete(ctx, uuid)
+	check := func(incident *models.Incident) error {
+		return checkVersion(incident, ifMatch)
+	}
+	deleted, err := s.repo.Delete(ctx, auth.TenantFromContext(ctx), uuid, actorFromContext(ctx), check)
+	if err != nil {
+		return err
+	}
+	s.index.Remove(ctx, uuid)
+	s.events.Publish(ctx, models.EventIncidentDeleted, *deleted)
+
+	return nil
+}
+
+// GetByIDAsOf возвращает инцидент в том виде, в каком он был в момент asOf
+func (s *IncidentService) GetByIDAsOf(ctx context.Context, id string, asOf time.Time) (*models.Incident, error) {
+	uuid, err := parseUUID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetAsOf(ctx, auth.TenantFromContext(ctx), uuid, asOf)
+}
+
+// History возвращает версии инцидента начиная с последней; история удаленного инцидента сохраняется
+func (s *IncidentService) History(ctx context.Context, id string, page, limit int) ([]models.IncidentHistoryEntry, int, error) {
+	uuid, err := parseUUID(id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entries, total, err := s.repo.History(ctx, auth.TenantFromContext(ctx), uuid, (page-1)*limit, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return nil, 0, fmt.Errorf("incident not found")
+	}
+
+	return entries, total, nil
+}
+
+// SubscribeEvents подписывает клиента на поток изменений инцидентов арендатора
+func (s *IncidentService) SubscribeEvents(ctx context.Context, filter models.IncidentEventFilter, lastEventID string) (*EventSubscription, error) {
+	return s.events.Subscribe(ctx, filter, lastEventID)
 }
 
 func (s *IncidentService) GetActiveIncidents(ctx context.Context) ([]models.Incident, error) {
-	return s.repo.GetActiveIncidents(ctx)
+	return s.repo.GetActiveIncidents(ctx, auth.TenantFromContext(ctx))
+}
+
+// normalizeFilter разбирает список важностей через запятую и проверяет согласованность фильтров
+func normalizeFilter(filter *models.IncidentFilter) error {
+	severities, err := splitSeverities(filter.Severity)
+	if err != nil {
+		return err
+	}
+	filter.Severity = severities
+	filter.Query = strings.TrimSpace(filter.Query)
+
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && filter.CreatedTo.Before(*filter.CreatedFrom) {
+		return fmt.Errorf("%w: created_to must not be before created_from", ErrInvalidFilter)
+	}
+	if filter.UpdatedFrom != nil && filter.UpdatedTo != nil && filter.UpdatedTo.Before(*filter.UpdatedFrom) {
+		return fmt.Errorf("%w: updated_to must not be before updated_from", ErrInvalidFilter)
+	}
+
+	if _, err := filterBoundingBox(filter.MinLat, filter.MinLng, filter.MaxLat, filter.MaxLng); err != nil {
+		return err
+	}
+
+	if (filter.Lat == nil) != (filter.Lng == nil) {
+		return fmt.Errorf("%w: lat and lng must be set together", ErrInvalidFilter)
+	}
+	if filter.Lat != nil {
+		if err := validateCoordinates(*filter.Lat, *filter.Lng); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+		}
+	} else if filter.Radius != nil {
+		return fmt.Errorf("%w: radius requires lat and lng", ErrInvalidFilter)
+	} else if strings.TrimPrefix(filter.Sort, "-") == "distance" {
+		return fmt.Errorf("%w: sorting by distance requires lat and lng", ErrInvalidFilter)
+	}
+
+	return nil
+}
+
+// splitSeverities разбирает важности, переданные повторением параметра или через запятую
+func splitSeverities(values []string) ([]string, error) {
+	var severities []string
+	for _, value := range values {
+		for _, severity := range strings.Split(value, ",") {
+			severity = strings.TrimSpace(severity)
+			if severity == "" {
+				continue
+			}
+			if _, ok := severityRanks[severity]; !ok {
+				return nil, fmt.Errorf("%w: unknown severity %q", ErrInvalidFilter, severity)
+			}
+			severities = append(severities, severity)
+		}
+	}
+	return severities, nil
+}
+
+// filterBoundingBox собирает прямоугольник из параметров запроса; nil — прямоугольник не задан
+func filterBoundingBox(minLat, minLng, maxLat, maxLng *float64) (*models.BoundingBox, error) {
+	set := 0
+	for _, v := range []*float64{minLat, minLng, maxLat, maxLng} {
+		if v != nil {
+			set++
+		}
+	}
+	if set == 0 {
+		return nil, nil
+	}
+	if set != 4 {
+		return nil, fmt.Errorf("%w: min_lat, min_lng, max_lat and max_lng must be set together", ErrInvalidFilter)
+	}
+
+	bbox := &models.BoundingBox{MinLat: *minLat, MinLng: *minLng, MaxLat: *maxLat, MaxLng: *maxLng}
+	if err := validateBoundingBox(*bbox); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	return bbox, nil
+}
+
+// checkVersion проверяет, что версия инцидента входит в ifMatch; nil разрешает любую версию
+func checkVersion(incident *models.Incident, ifMatch []int) error {
+	if ifMatch == nil {
+		return nil
+	}
+	for _, version := range ifMatch {
+		if version == incident.Version {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: current version is %d", ErrVersionMismatch, incident.Version)
+}
+
+// actorFromContext возвращает автора изменения по ключу API запроса
+func actorFromContext(ctx context.Context) models.Actor {
+	principal := auth.FromContext(ctx)
+	if principal == nil {
+		return models.Actor{Name: "anonymous"}
+	}
+
+	actor := models.Actor{Name: principal.Name}
+	if principal.KeyID != uuid.Nil {
+		keyID := principal.KeyID
+		actor.KeyID = &keyID
+	}
+	return actor
+}
+
+// applyUpdate переносит изменения из запроса в инцидент и проверяет результат.
+// Передача координат или радиуса без геометрии превращает полигональную зону обратно в окружность.
+func applyUpdate(incident *models.Incident, req models.UpdateIncidentRequest) error {
+	if req.Title != nil {
+		incident.Title = *req.Title
+	}
+	if req.Description != nil {
+		incident.Description = *req.Description
+	}
+	if req.Severity != nil {
+		incident.Severity = *req.Severity
+	}
+	if req.Status != nil {
+		incident.Status = *req.Status
+	}
+	if req.StartsAt.Set {
+		incident.StartsAt = req.StartsAt.Value
+	}
+	if req.ExpiresAt.Set {
+		incident.ExpiresAt = req.ExpiresAt.Value
+	}
+	if err := validateSchedule(incident.StartsAt, incident.ExpiresAt); err != nil {
+		return err
+	}
+
+	if req.Geometry != nil {
+		if err := validateGeometry(req.Geometry); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidIncident, err)
+		}
+		incident.Geometry = req.Geometry
+		incident.Latitude, incident.Longitude, incident.Radius = geometryCircle(req.Geometry)
+		return nil
+	}
+
+	if req.Latitude == nil && req.Longitude == nil && req.Radius == nil {
+		return nil
+	}
+	if req.Latitude != nil {
+		incident.Latitude = *req.Latitude
+	}
+	if req.Longitude != nil {
+		incident.Longitude = *req.Longitude
+	}
+	if req.Radius != nil {
+		incident.Radius = *req.Radius
+	}
+	if incident.Geometry != nil && req.Radius == nil {
+		return fmt.Errorf("%w: radius is required to convert a polygon zone into a circle", ErrInvalidIncident)
+	}
+	incident.Geometry = nil
+
+	return validateCircle(incident.Latitude, incident.Longitude, incident.Radius)
+}
+
+// prepareCreate проверяет запрос на создание; для полигона заполняет центр и радиус описанной окружности
+func prepareCreate(req *models.CreateIncidentRequest) error {
+	if req.Geometry != nil {
+		if err := validateGeometry(req.Geometry); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidIncident, err)
+		}
+		lat, lng, radius := geometryCircle(req.Geometry)
+		req.Latitude, req.Longitude, req.Radius = &lat, &lng, &radius
+	} else {
+		if req.Latitude == nil || req.Longitude == nil || req.Radius == nil {
+			return fmt.Errorf("%w: latitude, longitude and radius are required when geometry is not set", ErrInvalidIncident)
+		}
+		if err := validateCircle(*req.Latitude, *req.Longitude, *req.Radius); err != nil {
+			return err
+		}
+	}
+	if err := validateSchedule(req.StartsAt, req.ExpiresAt); err != nil {
+		return err
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("%w: expires_at must be in the future", ErrInvalidIncident)
+	}
+	return nil
+}
+
+func validateCircle(lat, lng, radius float64) error {
+	if err := validateCoordinates(lat, lng); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidIncident, err)
+	}
+	if radius <= 0 {
+		return fmt.Errorf("%w: radius must be greater than 0 when geometry is not set", ErrInvalidIncident)
+	}
+	return nil
+}
+
+func validateSchedule(startsAt, expiresAt *time.Time) error {
+	if startsAt != nil && expiresAt != nil && !expiresAt.After(*startsAt) {
+		return fmt.Errorf("%w: expires_at must be after starts_at", ErrInvalidIncident)
+	}
+	return nil
 }
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"geo_system_core/internal/auth"
+	"geo_system_core/internal/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestActorFromContext(t *testing.T) {
+	keyID := uuid.New()
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		expected  string
+		hasKeyID  bool
+	}{
+		{name: "Ключ из БД", principal: &auth.Principal{KeyID: keyID, Name: "operator"}, expected: "operator", hasKeyID: true},
+		{name: "Начальный ключ без ID", principal: &auth.Principal{Name: "bootstrap"}, expected: "bootstrap", hasKeyID: false},
+		{name: "Запрос без ключа", principal: nil, expected: "anonymous", hasKeyID: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = auth.WithPrincipal(ctx, tt.principal)
+			}
+
+			actor := actorFromContext(ctx)
+			if actor.Name != tt.expected {
+				t.Errorf("actorFromContext().Name = %s, expected %s", actor.Name, tt.expected)
+			}
+			if (actor.KeyID != nil) != tt.hasKeyID {
+				t.Errorf("actorFromContext().KeyID = %v, expected key id: %v", actor.KeyID, tt.hasKeyID)
+			}
+			if tt.hasKeyID && *actor.KeyID != keyID {
+				t.Errorf("actorFromContext().KeyID = %s, expected %s", actor.KeyID, keyID)
+			}
+		})
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	incident := &models.Incident{Version: 3}
+
+	tests := []struct {
+		name     string
+		ifMatch  []int
+		mismatch bool
+	}{
+		{name: "Без If-Match", ifMatch: nil, mismatch: false},
+		{name: "Версия совпадает", ifMatch: []int{3}, mismatch: false},
+		{name: "Одна из версий совпадает", ifMatch: []int{2, 3}, mismatch: false},
+		{name: "Версия устарела", ifMatch: []int{2}, mismatch: true},
+		{name: "Нет распознанных версий", ifMatch: []int{}, mismatch: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkVersion(incident, tt.ifMatch)
+			if errors.Is(err, ErrVersionMismatch) != tt.mismatch {
+				t.Errorf("checkVersion() error = %v, expected mismatch: %v", err, tt.mismatch)
+			}
+		})
+	}
+}
+
+func TestNormalizeFilter(t *testing.T) {
+	float := func(v float64) *float64 { return &v }
+	from := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	to := from.Add(-time.Hour)
+
+	tests := []struct {
+		name       string
+		filter     models.IncidentFilter
+		valid      bool
+		severities []string
+	}{
+		{name: "Пустой фильтр", filter: models.IncidentFilter{}, valid: true},
+		{
+			name:       "Важность через запятую и повтором",
+			filter:     models.IncidentFilter{Severity: []string{"high, critical", "low"}},
+			valid:      true,
+			severities: []string{"high", "critical", "low"},
+		},
+		{name: "Неизвестная важность", filter: models.IncidentFilter{Severity: []string{"high,urgent"}}, valid: false},
+		{name: "Начало периода позже конца", filter: models.IncidentFilter{CreatedFrom: &from, CreatedTo: &to}, valid: false},
+		{name: "Неполный прямоугольник", filter: models.IncidentFilter{MinLat: float(55), MaxLat: float(56)}, valid: false},
+		{
+			name:   "Прямоугольник",
+			filter: models.IncidentFilter{MinLat: float(55), MinLng: float(37), MaxLat: float(56), MaxLng: float(38)},
+			valid:  true,
+		},
+		{
+			name:   "Перевернутый прямоугольник",
+			filter: models.IncidentFilter{MinLat: float(56), MinLng: float(37), MaxLat: float(55), MaxLng: float(38)},
+			valid:  false,
+		},
+		{name: "Точка без долготы", filter: models.IncidentFilter{Lat: float(55)}, valid: false},
+		{name: "Радиус без точки", filter: models.IncidentFilter{Radius: float(500)}, valid: false},
+		{name: "Радиус вокруг точки", filter: models.IncidentFilter{Lat: float(55), Lng: float(37), Radius: float(500)}, valid: true},
+		{name: "Сортировка по расстоянию без точки", filter: models.IncidentFilter{Sort: "-distance"}, valid: false},
+		{name: "Сортировка по расстоянию от точки", filter: models.IncidentFilter{Lat: float(55), Lng: float(37), Sort: "distance"}, valid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			err := normalizeFilter(&filter)
+			if (err == nil) != tt.valid {
+				t.Fatalf("normalizeFilter() error = %v, expected valid: %v", err, tt.valid)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidFilter) {
+				t.Errorf("normalizeFilter() error = %v, expected ErrInvalidFilter", err)
+			}
+			if tt.severities != nil && strings.Join(filter.Severity, ",") != strings.Join(tt.severities, ",") {
+				t.Errorf("normalizeFilter() severity = %v, expected %v", filter.Severity, tt.severities)
+			}
+		})
+	}
+}
+
+func TestPrepareCreate(t *testing.T) {
+	float := func(v float64) *float64 { return &v }
+	square := `{"type":"Polygon","coordinates":[[[37.60,55.74],[37.62,55.74],[37.62,55.76],[37.60,55.76],[37.60,55.74]]]}`
+
+	tests := []struct {
+		name  string
+		req   models.CreateIncidentRequest
+		valid bool
+	}{
+		{name: "Окружность", req: models.CreateIncidentRequest{Latitude: float(55.75), Longitude: float(37.61), Radius: float(500)}, valid: true},
+		{name: "Окружность на нулевом меридиане и экваторе", req: models.CreateIncidentRequest{Latitude: float(0), Longitude: float(0), Radius: float(500)}, valid: true},
+		{name: "Окружность без координат", req: models.CreateIncidentRequest{Radius: float(500)}, valid: false},
+		{name: "Окружность без долготы", req: models.CreateIncidentRequest{Latitude: float(55.75), Radius: float(500)}, valid: false},
+		{name: "Окружность без радиуса", req: models.CreateIncidentRequest{Latitude: float(55.75), Longitude: float(37.61)}, valid: false},
+		{name: "Полигон без координат", req: models.CreateIncidentRequest{Geometry: mustGeometry(t, square)}, valid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			err := prepareCreate(&req)
+			if (err == nil) != tt.valid {
+				t.Fatalf("prepareCreate() error = %v, expected valid: %v", err, tt.valid)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrInvalidIncident) {
+					t.Errorf("prepareCreate() error = %v, expected ErrInvalidIncident", err)
+				}
+				return
+			}
+			if req.Latitude == nil || req.Longitude == nil || req.Radius == nil {
+				t.Errorf("prepareCreate() left the circle unset: %+v", req)
+			}
+		})
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		startsAt  *time.Time
+		expiresAt *time.Time
+		valid     bool
+	}{
+		{name: "Без ограничений", valid: true},
+		{name: "Только начало", startsAt: &now, valid: true},
+		{name: "Только окончание", expiresAt: &later, valid: true},
+		{name: "Окончание после начала", startsAt: &now, expiresAt: &later, valid: true},
+		{name: "Окончание равно началу", startsAt: &now, expiresAt: &now, valid: false},
+		{name: "Окончание раньше начала", startsAt: &later, expiresAt: &now, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSchedule(tt.startsAt, tt.expiresAt)
+			if (err == nil) != tt.valid {
+				t.Fatalf("validateSchedule() error = %v, expected valid: %v", err, tt.valid)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidIncident) {
+				t.Errorf("validateSchedule() error = %v, expected ErrInvalidIncident", err)
+			}
+		})
+	}
+}
+
+func TestApplyUpdateSchedule(t *testing.T) {
+	startsAt := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2024, 6, 1, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		body      string
+		startsAt  *time.Time
+		expiresAt *time.Time
+		valid     bool
+	}{
+		{name: "Поля не переданы", body: `{}`, startsAt: &startsAt, expiresAt: &expiresAt, valid: true},
+		{name: "Сброс окончания", body: `{"expires_at": null}`, startsAt: &startsAt, valid: true},
+		{name: "Сброс обоих полей", body: `{"starts_at": null, "expires_at": null}`, valid: true},
+		{name: "Новое окончание", body: `{"expires_at": "2024-06-02T20:00:00Z"}`, startsAt: &startsAt, expiresAt: timePtr(expiresAt.Add(24 * time.Hour)), valid: true},
+		{name: "Начало после окончания", body: `{"starts_at": "2024-06-02T08:00:00Z"}`, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req models.UpdateIncidentRequest
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatalf("failed to unmarshal request: %v", err)
+			}
+			incident := &models.Incident{Latitude: 55.75, Longitude: 37.61, Radius: 500, StartsAt: &startsAt, ExpiresAt: &expiresAt}
+
+			err := applyUpdate(incident, req)
+			if (err == nil) != tt.valid {
+				t.Fatalf("applyUpdate() error = %v, expected valid: %v", err, tt.valid)
+			}
+			if err != nil {
+				return
+			}
+			if !equalTime(incident.StartsAt, tt.startsAt) || !equalTime(incident.ExpiresAt, tt.expiresAt) {
+				t.Errorf("applyUpdate() schedule = %v - %v, expected %v - %v", incident.StartsAt, incident.ExpiresAt, tt.startsAt, tt.expiresAt)
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
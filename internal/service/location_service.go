@@ -2,29 +2,60 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"geo_system_core/internal/auth"
+	"geo_system_core/internal/config"
+	"geo_system_core/internal/metrics"
 	"geo_system_core/internal/models"
 	"geo_system_core/internal/repository/postgres"
-	"geo_system_core/internal/repository/redis"
 	"math"
+	"sort"
 	"time"
 )
 
+// ErrInvalidCheckFilter — недопустимый фильтр журнала проверок
+var ErrInvalidCheckFilter = errors.New("invalid location check filter")
+
+// Зависимости сервиса проверок; им соответствуют *postgres.IncidentRepository
+// и *postgres.LocationRepository
+type (
+	incidentFinder interface {
+		FindNearby(ctx context.Context, tenantID string, lat, lng, maxDistance float64) ([]models.Incident, error)
+		GetActiveIncidents(ctx context.Context, tenantID string) ([]models.Incident, error)
+		GetActiveIncidentsAt(ctx context.Context, tenantID string, at time.Time) ([]models.Incident, error)
+	}
+	checkStore interface {
+		SaveCheck(ctx context.Context, tenantID, userID string, lat, lng float64, hasDanger bool) error
+		SaveChecks(ctx context.Context, checks []models.LocationCheckLog) error
+		ListChecks(ctx context.Context, tenantID string, filter models.LocationCheckFilter, after *models.Cursor, limit int) ([]models.LocationCheckLog, error)
+	}
+)
+
 type LocationService struct {
-	incidentRepo *postgres.IncidentRepository
-	locationRepo *postgres.LocationRepository
-	queueRepo    *redis.QueueRepository
+	incidentRepo incidentFinder
+	locationRepo checkStore
+	index        *IncidentIndex
+	geofence     *GeofenceService
+	background   *Background
+	config       *config.LocationConfig
 }
 
 func NewLocationService(
 	incidentRepo *postgres.IncidentRepository,
 	locationRepo *postgres.LocationRepository,
-	queueRepo *redis.QueueRepository,
+	index *IncidentIndex,
+	geofence *GeofenceService,
+	background *Background,
+	cfg *config.LocationConfig,
 ) *LocationService {
 	return &LocationService{
 		incidentRepo: incidentRepo,
 		locationRepo: locationRepo,
-		queueRepo:    queueRepo,
+		index:        index,
+		geofence:     geofence,
+		background:   background,
+		config:       cfg,
 	}
 }
 
@@ -45,69 +76,301 @@ func CalculateDistance(lat1, lon1, lat2, lon2 float64) float64 {
 	return earthRadius * c
 }
 
+// CheckLocation проверяет точку по инцидентам арендатора из контекста запроса
 func (s *LocationService) CheckLocation(ctx context.Context, req models.LocationCheckRequest) (*models.LocationCheckResponse, error) {
-	// Валидация координат
-	if req.Latitude < -90 || req.Latitude > 90 {
-		return nil, fmt.Errorf("invalid latitude: must be between -90 and 90")
+	// Валидация координат и параметров движения
+	if err := validateLocationRequest(req); err != nil {
+		return nil, err
 	}
-	if req.Longitude < -180 || req.Longitude > 180 {
-		return nil, fmt.Errorf("invalid longitude: must be between -180 and 180")
+	tenantID := auth.TenantFromContext(ctx)
+	options := s.options(req)
+
+	incidents, err := s.nearby(ctx, tenantID, req.Latitude, req.Longitude, options.searchDistance(req))
+	if err != nil {
+		return nil, err
 	}
 
-	// Ищем ближайшие инциденты (в радиусе 10 км для оптимизации)
-	maxSearchDistance := 10000.0 // 10 км
-	incidents, err := s.incidentRepo.FindNearby(ctx, req.Latitude, req.Longitude, maxSearchDistance)
+	response := evaluateLocation(req, incidents, options)
+	observeCheck(response)
+
+	// Сохраняем факт проверки в БД (асинхронно через горутину)
+	s.background.Run(func(ctx context.Context) {
+		_ = s.locationRepo.SaveCheck(ctx, tenantID, req.UserID, req.Latitude, req.Longitude, response.HasDanger)
+	})
+
+	// Сравниваем зоны с предыдущей проверкой и ставим в очередь вебхуки о входе и выходе;
+	// зоны possibly_inside событий не вызывают
+	s.background.Run(func(ctx context.Context) {
+		_ = s.geofence.Process(ctx, tenantID, req.UserID, req.Latitude, req.Longitude, insideZones(response.NearbyIncidents))
+	})
+
+	return response, nil
+}
+
+// Evaluate проверяет точку как CheckLocation, но без записи проверки и событий зон
+func (s *LocationService) Evaluate(ctx context.Context, req models.LocationCheckRequest) (*models.LocationCheckResponse, error) {
+	if err := validateLocationRequest(req); err != nil {
+		return nil, err
+	}
+	options := s.options(req)
+	incidents, err := s.nearby(ctx, auth.TenantFromContext(ctx), req.Latitude, req.Longitude, options.searchDistance(req))
 	if err != nil {
-		return nil, fmt.Errorf("failed to find nearby incidents: %w", err)
+		return nil, err
+	}
+	return evaluateLocation(req, incidents, options), nil
+}
+
+// evaluateOptions — параметры оценки точки относительно зон
+type evaluateOptions struct {
+	warningDistance    float64
+	insideConfidence   float64
+	possibleConfidence float64
+}
+
+// searchDistance — на каком расстоянии от точки искать границы зон: с учетом точности координат
+// в пределах 3·accuracy лежат все зоны, вероятность нахождения в которых заметно отлична от нуля
+func (o evaluateOptions) searchDistance(req models.LocationCheckRequest) float64 {
+	return math.Max(o.warningDistance, 3*requestAccuracy(req))
+}
+
+// options возвращает параметры оценки для запроса; дистанция предупреждения из запроса
+// ограничивается LOCATION_MAX_WARNING_DISTANCE
+func (s *LocationService) options(req models.LocationCheckRequest) evaluateOptions {
+	distance := s.config.WarningDistance
+	if req.WarningDistance != nil {
+		distance = *req.WarningDistance
+	}
+	return evaluateOptions{
+		warningDistance:    math.Max(0, math.Min(distance, s.config.MaxWarningDistance)),
+		insideConfidence:   s.config.InsideConfidence,
+		possibleConfidence: s.config.PossibleConfidence,
+	}
+}
+
+func requestAccuracy(req models.LocationCheckRequest) float64 {
+	if req.Accuracy == nil {
+		return 0
 	}
+	return *req.Accuracy
+}
+
+// nearby ищет в индексе инциденты, граница которых не дальше maxSearchDistance метров от точки;
+// пока индекс не загружен, обращается к БД
+func (s *LocationService) nearby(ctx context.Context, tenantID string, lat, lng, maxSearchDistance float64) ([]models.Incident, error) {
+	start := time.Now()
+	source := "index"
+	incidents, ok := s.index.Nearby(tenantID, lat, lng, maxSearchDistance, start)
+	if !ok {
+		source = "database"
+		var err error
+		incidents, err = s.incidentRepo.FindNearby(ctx, tenantID, lat, lng, maxSearchDistance)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find nearby incidents: %w", err)
+		}
+	}
+	metrics.LocationQueryDuration.WithLabelValues(source).Observe(time.Since(start).Seconds())
+
+	return incidents, nil
+}
+
+// CheckLocationBatch проверяет пакет координат по одному снимку активных инцидентов.
+// Ошибка валидации элемента возвращается в его результате; проверки сохраняются одной вставкой.
+func (s *LocationService) CheckLocationBatch(ctx context.Context, items []models.LocationCheckRequest) (*models.BatchLocationCheckResponse, error) {
+	tenantID := auth.TenantFromContext(ctx)
+	now := time.Now()
+	source := "index"
+	incidents, ok := s.index.Active(tenantID, now)
+	if !ok {
+		source = "database"
+		var err error
+		incidents, err = s.incidentRepo.GetActiveIncidents(ctx, tenantID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get active incidents: %w", err)
+		}
+	}
+	metrics.LocationQueryDuration.WithLabelValues(source).Observe(time.Since(now).Seconds())
+
+	results := make([]models.BatchLocationCheckResult, len(items))
+	checks := make([]models.LocationCheckLog, 0, len(items))
+	var evaluated []int
+
+	for i, req := range items {
+		results[i].Index = i
+
+		if err := validateLocationRequest(req); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+
+		response := evaluateLocation(req, incidents, s.options(req))
+		observeCheck(response)
+		results[i].Result = response
+		evaluated = append(evaluated, i)
+		checks = append(checks, models.LocationCheckLog{
+			TenantID:  tenantID,
+			UserID:    req.UserID,
+			Latitude:  req.Latitude,
+			Longitude: req.Longitude,
+			HasDanger: response.HasDanger,
+			CreatedAt: now,
+		})
+	}
+
+	if len(checks) > 0 {
+		s.background.Run(func(ctx context.Context) {
+			_ = s.locationRepo.SaveChecks(ctx, checks)
+		})
 
+		// События зон обрабатываются последовательно, чтобы несколько точек
+		// одного пользователя в пакете учитывались по порядку
+		s.background.Run(func(ctx context.Context) {
+			for _, i := range evaluated {
+				req := items[i]
+				_ = s.geofence.Process(ctx, tenantID, req.UserID, req.Latitude, req.Longitude, insideZones(results[i].Result.NearbyIncidents))
+			}
+		})
+	}
+
+	return &models.BatchLocationCheckResponse{Results: results}, nil
+}
+
+// ListChecks возвращает страницу журнала проверок арендатора и курсор следующей страницы
+func (s *LocationService) ListChecks(ctx context.Context, filter models.LocationCheckFilter, cursor string, limit int) ([]models.LocationCheckLog, string, error) {
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return nil, "", fmt.Errorf("%w: to must not be before from", ErrInvalidCheckFilter)
+	}
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	if limit < 1 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	checks, err := s.locationRepo.ListChecks(ctx, auth.TenantFromContext(ctx), filter, after, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(checks) > limit {
+		checks = checks[:limit]
+		last := checks[limit-1]
+		next = encodeCursor(models.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	return checks, next, nil
+}
+
+func observeCheck(response *models.LocationCheckResponse) {
+	result := "safe"
+	if response.HasDanger {
+		result = "danger"
+	}
+	metrics.LocationChecks.WithLabelValues(result).Inc()
+}
+
+func validateLocationRequest(req models.LocationCheckRequest) error {
+	if req.UserID == "" {
+		return fmt.Errorf("user_id is required")
+	}
+	if req.WarningDistance != nil && *req.WarningDistance < 0 {
+		return fmt.Errorf("invalid warning_distance: must not be negative")
+	}
+	if req.Heading != nil && (*req.Heading < 0 || *req.Heading >= 360) {
+		return fmt.Errorf("invalid heading: must be between 0 and 360")
+	}
+	if req.Speed != nil && *req.Speed < 0 {
+		return fmt.Errorf("invalid speed: must not be negative")
+	}
+	if req.Accuracy != nil && *req.Accuracy < 0 {
+		return fmt.Errorf("invalid accuracy: must not be negative")
+	}
+	return validateCoordinates(req.Latitude, req.Longitude)
+}
+
+// evaluateLocation классифицирует зоны относительно точки. В nearby_incidents попадают зоны inside
+// и possibly_inside, в approaching — зоны outside, граница которых ближе дистанции предупреждения.
+func evaluateLocation(req models.LocationCheckRequest, incidents []models.Incident, options evaluateOptions) *models.LocationCheckResponse {
 	var nearbyIncidents []models.NearbyIncident
+	var approaching []models.ApproachingIncident
 	hasDanger := false
+	accuracy := requestAccuracy(req)
 
 	for _, incident := range incidents {
 		distance := CalculateDistance(req.Latitude, req.Longitude, incident.Latitude, incident.Longitude)
 
-		// Проверяем, находится ли пользователь в радиусе опасности
-		if distance <= incident.Radius {
+		// Проверяем, находится ли пользователь в зоне опасности (окружность или полигон)
+		contains := incidentContains(&incident, req.Latitude, req.Longitude, distance)
+		confidence := 0.0
+		if contains {
+			confidence = 1
+		}
+		// Граница зоны не ближе расстояния до описанной окружности; дальше 3·accuracy вероятность пренебрежимо мала
+		if accuracy > 0 && distance-incident.Radius <= 3*accuracy {
+			confidence = insideConfidence(boundaryDepth(&incident, req.Latitude, req.Longitude, distance, contains), accuracy)
+		}
+
+		zone := nearbyIncident(&incident, distance)
+		zone.Confidence = &confidence
+		switch {
+		case confidence > 0 && confidence >= options.insideConfidence:
 			hasDanger = true
-			nearbyIncidents = append(nearbyIncidents, models.NearbyIncident{
-				ID:          incident.ID,
-				Title:       incident.Title,
-				Description: incident.Description,
-				Latitude:    incident.Latitude,
-				Longitude:   incident.Longitude,
-				Radius:      incident.Radius,
-				Severity:    incident.Severity,
-				Distance:    distance,
-			})
+			zone.Classification = models.ZoneInside
+			nearbyIncidents = append(nearbyIncidents, *zone)
+			continue
+		case confidence > 0 && confidence >= options.possibleConfidence:
+			zone.Classification = models.ZonePossiblyInside
+			nearbyIncidents = append(nearbyIncidents, *zone)
+			continue
+		}
+
+		// Точка внутри зоны, но уверенность ниже порогов — зона не считается ни опасной, ни приближающейся
+		if contains || distance-incident.Radius > options.warningDistance {
+			continue
+		}
+		if zone := approachIncident(&incident, req, distance); zone.BoundaryDistance <= options.warningDistance {
+			zone.Classification = models.ZoneOutside
+			zone.Confidence = &confidence
+			approaching = append(approaching, zone)
 		}
 	}
+	sort.Slice(approaching, func(i, j int) bool {
+		return approaching[i].BoundaryDistance < approaching[j].BoundaryDistance
+	})
 
-	response := &models.LocationCheckResponse{
+	return &models.LocationCheckResponse{
 		NearbyIncidents: nearbyIncidents,
+		Approaching:     approaching,
 		HasDanger:       hasDanger,
 	}
+}
 
-	// Сохраняем факт проверки в БД (асинхронно через горутину)
-	go func() {
-		ctx := context.Background()
-		_ = s.locationRepo.SaveCheck(ctx, req.UserID, req.Latitude, req.Longitude, hasDanger)
-	}()
-
-	// Если есть опасности, ставим задачу на отправку вебхука
-	if hasDanger {
-		go func() {
-			ctx := context.Background()
-			payload := models.WebhookPayload{
-				UserID:    req.UserID,
-				Latitude:  req.Latitude,
-				Longitude: req.Longitude,
-				Timestamp: time.Now(),
-				Incidents: nearbyIncidents,
-			}
-			_ = s.queueRepo.EnqueueWebhook(ctx, payload)
-		}()
+// insideZones отбирает зоны с классификацией inside
+func insideZones(zones []models.NearbyIncident) []models.NearbyIncident {
+	var inside []models.NearbyIncident
+	for _, zone := range zones {
+		if zone.Classification == models.ZoneInside {
+			inside = append(inside, zone)
+		}
 	}
+	return inside
+}
 
-	return response, nil
+// nearbyIncident описывает зону инцидента для ответа; distance — расстояние до центра зоны
+func nearbyIncident(incident *models.Incident, distance float64) *models.NearbyIncident {
+	return &models.NearbyIncident{
+		ID:          incident.ID,
+		Title:       incident.Title,
+		Description: incident.Description,
+		Latitude:    incident.Latitude,
+		Longitude:   incident.Longitude,
+		Radius:      incident.Radius,
+		Geometry:    incident.Geometry,
+		Severity:    incident.Severity,
+		Distance:    distance,
+	}
 }
@@ -1,7 +1,16 @@
 package service
 
 import (
+	"context"
+	"geo_system_core/internal/auth"
+	"geo_system_core/internal/config"
+	"geo_system_core/internal/models"
+	"geo_system_core/internal/redistest"
+	"geo_system_core/internal/repository/redis"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 func TestCalculateDistance(t *testing.T) {
@@ -48,3 +57,176 @@ func TestCalculateDistance(t *testing.T) {
 		})
 	}
 }
+
+// fakeIncidentFinder отдает инциденты вместо Postgres, пока индекс не загружен
+type fakeIncidentFinder struct {
+	incidents []models.Incident
+}
+
+func (f *fakeIncidentFinder) FindNearby(ctx context.Context, tenantID string, lat, lng, maxDistance float64) ([]models.Incident, error) {
+	return f.incidents, nil
+}
+
+func (f *fakeIncidentFinder) GetActiveIncidents(ctx context.Context, tenantID string) ([]models.Incident, error) {
+	return f.incidents, nil
+}
+
+func (f *fakeIncidentFinder) GetActiveIncidentsAt(ctx context.Context, tenantID string, at time.Time) ([]models.Incident, error) {
+	return f.incidents, nil
+}
+
+type fakeCheckStore struct {
+	saved [][]models.LocationCheckLog
+}
+
+func (f *fakeCheckStore) SaveCheck(ctx context.Context, tenantID, userID string, lat, lng float64, hasDanger bool) error {
+	return nil
+}
+
+func (f *fakeCheckStore) SaveChecks(ctx context.Context, checks []models.LocationCheckLog) error {
+	f.saved = append(f.saved, checks)
+	return nil
+}
+
+func (f *fakeCheckStore) ListChecks(ctx context.Context, tenantID string, filter models.LocationCheckFilter, after *models.Cursor, limit int) ([]models.LocationCheckLog, error) {
+	return nil, nil
+}
+
+func TestCheckLocationBatch(t *testing.T) {
+	float := func(v float64) *float64 { return &v }
+	zone := models.Incident{
+		ID: uuid.New(), TenantID: "north", Title: "Пожар", Severity: "high", Latitude: 55.75, Longitude: 37.61, Radius: 500,
+		Status: "active", IsActive: true,
+	}
+	inside := func(userID string) models.LocationCheckRequest {
+		return models.LocationCheckRequest{UserID: userID, Latitude: 55.75, Longitude: 37.61}
+	}
+	outside := func(userID string) models.LocationCheckRequest {
+		return models.LocationCheckRequest{UserID: userID, Latitude: 55.80, Longitude: 37.61}
+	}
+	invalid := func(req models.LocationCheckRequest, change func(*models.LocationCheckRequest)) models.LocationCheckRequest {
+		change(&req)
+		return req
+	}
+
+	tests := []struct {
+		name   string
+		items  []models.LocationCheckRequest
+		errors []bool // у каких элементов ожидается ошибка валидации
+		danger []bool
+		events []string // вебхуки событий зон в порядке постановки в очередь: пользователь и событие
+	}{
+		{
+			name: "Ошибки валидации отдельных элементов",
+			items: []models.LocationCheckRequest{
+				inside("user-1"),
+				invalid(inside("user-1"), func(r *models.LocationCheckRequest) { r.Latitude = 100 }),
+				invalid(inside("user-1"), func(r *models.LocationCheckRequest) { r.UserID = "" }),
+				invalid(inside("user-1"), func(r *models.LocationCheckRequest) { r.Accuracy = float(-1) }),
+				invalid(inside("user-1"), func(r *models.LocationCheckRequest) { r.Heading = float(360) }),
+			},
+			errors: []bool{false, true, true, true, true},
+			danger: []bool{true, false, false, false, false},
+			events: []string{"user-1 zone.enter"},
+		},
+		{
+			name: "Смешанный пакет нескольких пользователей",
+			items: []models.LocationCheckRequest{
+				inside("user-1"),
+				outside("user-2"),
+				invalid(outside("user-3"), func(r *models.LocationCheckRequest) { r.Longitude = -200 }),
+				inside("user-2"),
+			},
+			errors: []bool{false, false, true, false},
+			danger: []bool{true, false, false, true},
+			events: []string{"user-1 zone.enter", "user-2 zone.enter"},
+		},
+		{
+			name:   "Точки одного пользователя обрабатываются по порядку",
+			items:  []models.LocationCheckRequest{inside("user-1"), outside("user-1"), inside("user-1"), outside("user-1")},
+			errors: []bool{false, false, false, false},
+			danger: []bool{true, false, true, false},
+			events: []string{"user-1 zone.enter", "user-1 zone.exit", "user-1 zone.enter", "user-1 zone.exit"},
+		},
+		{
+			name:   "Все элементы с ошибками",
+			items:  []models.LocationCheckRequest{invalid(inside("user-1"), func(r *models.LocationCheckRequest) { r.Speed = float(-5) })},
+			errors: []bool{true},
+			danger: []bool{false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeCheckStore{}
+			client := redistest.NewServer(t).NewClient(t)
+			queue := redis.NewQueueRepository(client)
+			background := NewBackground()
+			s := &LocationService{
+				incidentRepo: &fakeIncidentFinder{incidents: []models.Incident{zone}},
+				locationRepo: store,
+				index:        NewIncidentIndex(nil, nil, 0), // не загружен — инциденты берутся из incidentRepo
+				geofence:     NewGeofenceService(redis.NewGeofenceRepository(client), queue, 0, time.Hour),
+				background:   background,
+				config:       &config.LocationConfig{WarningDistance: 1000, MaxWarningDistance: 10000, InsideConfidence: 0.5, PossibleConfidence: 0.05},
+			}
+
+			response, err := s.CheckLocationBatch(auth.WithTenant(context.Background(), "north"), tt.items)
+			if err != nil {
+				t.Fatalf("CheckLocationBatch() error = %v", err)
+			}
+			if err := background.Shutdown(context.Background()); err != nil {
+				t.Fatalf("Shutdown() error = %v", err)
+			}
+
+			if len(response.Results) != len(tt.items) {
+				t.Fatalf("CheckLocationBatch() results = %d, expected %d", len(response.Results), len(tt.items))
+			}
+			valid := 0
+			for i, result := range response.Results {
+				if result.Index != i {
+					t.Errorf("result %d index = %d", i, result.Index)
+				}
+				if (result.Error != "") != tt.errors[i] || (result.Result == nil) != tt.errors[i] {
+					t.Errorf("result %d = error %q, result %v, expected error: %v", i, result.Error, result.Result, tt.errors[i])
+					continue
+				}
+				if result.Result != nil {
+					valid++
+					if result.Result.HasDanger != tt.danger[i] {
+						t.Errorf("result %d has_danger = %v, expected %v", i, result.Result.HasDanger, tt.danger[i])
+					}
+				}
+			}
+
+			// Проверки сохраняются одной вставкой только для элементов без ошибок
+			if valid == 0 {
+				if len(store.saved) != 0 {
+					t.Errorf("SaveChecks() called %d times, expected none", len(store.saved))
+				}
+			} else if len(store.saved) != 1 || len(store.saved[0]) != valid {
+				t.Errorf("SaveChecks() calls = %v, expected one call with %d checks", store.saved, valid)
+			}
+
+			var events []string
+			for {
+				job, err := queue.DequeueWebhook(context.Background(), time.Minute)
+				if err != nil {
+					t.Fatalf("DequeueWebhook() error = %v", err)
+				}
+				if job == nil {
+					break
+				}
+				events = append(events, job.Payload.UserID+" "+job.Payload.Event)
+			}
+			if len(events) != len(tt.events) {
+				t.Fatalf("geofence events = %v, expected %v", events, tt.events)
+			}
+			for i := range tt.events {
+				if events[i] != tt.events[i] {
+					t.Errorf("geofence event %d = %s, expected %s", i, events[i], tt.events[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"geo_system_core/internal/models"
+	"math"
+)
+
+// Расчеты приближения к зоне выполняются в локальной плоской проекции с началом в точке пользователя:
+// x — на восток, y — на север, в метрах. На расстояниях дистанции предупреждения погрешность мала.
+type planePoint struct {
+	x, y float64
+}
+
+func projectPoint(originLat, originLng, lat, lng float64) planePoint {
+	return planePoint{
+		x: (lng - originLng) * metersPerDegree * math.Cos(originLat*math.Pi/180),
+		y: (lat - originLat) * metersPerDegree,
+	}
+}
+
+func (p planePoint) length() float64 {
+	return math.Hypot(p.x, p.y)
+}
+
+// azimuth возвращает азимут направления из начала координат на p в градусах от севера [0, 360)
+func (p planePoint) azimuth() float64 {
+	bearing := math.Atan2(p.x, p.y) * 180 / math.Pi
+	if bearing < 0 {
+		bearing += 360
+	}
+	return bearing
+}
+
+// approachIncident описывает приближение точки к зоне, внутри которой она не находится.
+// distance — расстояние до центра зоны по формуле гаверсинуса. Время до входа оценивается,
+// если заданы направление и положительная скорость и луч по направлению пересекает границу.
+func approachIncident(incident *models.Incident, req models.LocationCheckRequest, distance float64) models.ApproachingIncident {
+	approaching := models.ApproachingIncident{NearbyIncident: *nearbyIncident(incident, distance)}
+
+	var direction *planePoint
+	if req.Heading != nil {
+		heading := *req.Heading * math.Pi / 180
+		direction = &planePoint{x: math.Sin(heading), y: math.Cos(heading)}
+	}
+	entry := math.Inf(1)
+
+	if incident.Geometry == nil {
+		center := projectPoint(req.Latitude, req.Longitude, incident.Latitude, incident.Longitude)
+		approaching.BoundaryDistance = math.Max(distance-incident.Radius, 0)
+		approaching.Bearing = center.azimuth()
+		if direction != nil {
+			entry = rayCircleEntry(*direction, center, incident.Radius)
+		}
+	} else {
+		nearest := math.Inf(1)
+		for _, polygon := range incident.Geometry.Polygons {
+			for _, ring := range polygon {
+				for i := 0; i+1 < len(ring); i++ {
+					a := projectPoint(req.Latitude, req.Longitude, ring[i].Lat(), ring[i].Lng())
+					b := projectPoint(req.Latitude, req.Longitude, ring[i+1].Lat(), ring[i+1].Lng())
+					closest := closestOnSegment(a, b)
+					if d := closest.length(); d < nearest {
+						nearest = d
+						approaching.Bearing = closest.azimuth()
+					}
+					if direction != nil {
+						entry = math.Min(entry, raySegmentEntry(*direction, a, b))
+					}
+				}
+			}
+		}
+		approaching.BoundaryDistance = nearest
+	}
+
+	if req.Speed != nil && *req.Speed > 0 && !math.IsInf(entry, 1) {
+		seconds := entry / *req.Speed
+		approaching.TimeToEntry = &seconds
+	}
+
+	return approaching
+}
+
+// accuracySigmas — во сколько раз радиус 68% круга точности больше стандартного отклонения
+// по одной оси для двумерного нормального распределения: sqrt(-2 ln 0.32)
+const accuracySigmas = 1.5096
+
+// insideConfidence возвращает вероятность того, что точка с заданной точностью находится в зоне.
+// depth — расстояние от точки до границы, положительное внутри зоны и отрицательное снаружи;
+// граница вблизи точки считается прямой, так что вероятность — функция нормального распределения.
+func insideConfidence(depth, accuracy float64) float64 {
+	if accuracy <= 0 {
+		if depth >= 0 {
+			return 1
+		}
+		return 0
+	}
+	sigma := accuracy / accuracySigmas
+	return 0.5 * math.Erfc(-depth/(sigma*math.Sqrt2))
+}
+
+// boundaryDepth возвращает расстояние от точки до границы зоны со знаком: положительное, если точка
+// внутри (contains), и отрицательное снаружи. distance — расстояние до центра зоны.
+func boundaryDepth(incident *models.Incident, lat, lng, distance float64, contains bool) float64 {
+	if incident.Geometry == nil {
+		return incident.Radius - distance
+	}
+
+	nearest := math.Inf(1)
+	for _, polygon := range incident.Geometry.Polygons {
+		for _, ring := range polygon {
+			for i := 0; i+1 < len(ring); i++ {
+				a := projectPoint(lat, lng, ring[i].Lat(), ring[i].Lng())
+				b := projectPoint(lat, lng, ring[i+1].Lat(), ring[i+1].Lng())
+				nearest = math.Min(nearest, closestOnSegment(a, b).length())
+			}
+		}
+	}
+	if !contains {
+		return -nearest
+	}
+	return nearest
+}
+
+// closestOnSegment возвращает ближайшую к началу координат точку отрезка ab
+func closestOnSegment(a, b planePoint) planePoint {
+	ab := planePoint{x: b.x - a.x, y: b.y - a.y}
+	lengthSq := ab.x*ab.x + ab.y*ab.y
+	if lengthSq == 0 {
+		return a
+	}
+	t := math.Max(0, math.Min(1, -(a.x*ab.x+a.y*ab.y)/lengthSq))
+	return planePoint{x: a.x + ab.x*t, y: a.y + ab.y*t}
+}
+
+// rayCircleEntry возвращает расстояние по лучу из начала координат до входа в окружность; +Inf — луч ее не пересекает
+func rayCircleEntry(direction, center planePoint, radius float64) float64 {
+	projection := direction.x*center.x + direction.y*center.y
+	discriminant := projection*projection - (center.x*center.x + center.y*center.y - radius*radius)
+	if discriminant < 0 {
+		return math.Inf(1)
+	}
+	t := projection - math.Sqrt(discriminant)
+	if t < 0 {
+		return math.Inf(1)
+	}
+	return t
+}
+
+// raySegmentEntry возвращает расстояние по лучу из начала координат до пересечения с отрезком ab; +Inf — пересечения нет
+func raySegmentEntry(direction, a, b planePoint) float64 {
+	ab := planePoint{x: b.x - a.x, y: b.y - a.y}
+	denominator := direction.x*ab.y - direction.y*ab.x
+	if denominator == 0 {
+		return math.Inf(1) // луч параллелен отрезку
+	}
+	t := (a.x*ab.y - a.y*ab.x) / denominator
+	u := (a.x*direction.y - a.y*direction.x) / denominator
+	if t < 0 || u < 0 || u > 1 {
+		return math.Inf(1)
+	}
+	return t
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"encoding/json"
+	"geo_system_core/internal/models"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestApproachIncident(t *testing.T) {
+	float := func(v float64) *float64 { return &v }
+	circle := &models.Incident{Latitude: 55.77, Longitude: 37.61, Radius: 1000}
+	square := &models.Incident{
+		Latitude:  55.765,
+		Longitude: 37.61,
+		Radius:    1000,
+		Geometry: &models.Geometry{Type: models.GeometryPolygon, Polygons: []models.Polygon{{{
+			{37.60, 55.76}, {37.62, 55.76}, {37.62, 55.77}, {37.60, 55.77}, {37.60, 55.76},
+		}}}},
+	}
+	toCircle := CalculateDistance(55.75, 37.61, circle.Latitude, circle.Longitude) - circle.Radius
+	toSquare := 0.01 * metersPerDegree
+
+	tests := []struct {
+		name     string
+		incident *models.Incident
+		heading  *float64
+		speed    *float64
+		boundary float64
+		bearing  float64
+		eta      *float64
+	}{
+		{name: "Окружность без движения", incident: circle, boundary: toCircle, bearing: 0},
+		{name: "Движение к окружности", incident: circle, heading: float(0), speed: float(10), boundary: toCircle, bearing: 0, eta: float(toCircle / 10)},
+		{name: "Движение от окружности", incident: circle, heading: float(180), speed: float(10), boundary: toCircle, bearing: 0},
+		{name: "Остановка", incident: circle, heading: float(0), speed: float(0), boundary: toCircle, bearing: 0},
+		{name: "Движение к полигону", incident: square, heading: float(10), speed: float(20), boundary: toSquare, bearing: 0, eta: float(toSquare / math.Cos(10*math.Pi/180) / 20)},
+		{name: "Движение мимо полигона", incident: square, heading: float(90), speed: float(20), boundary: toSquare, bearing: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := models.LocationCheckRequest{Latitude: 55.75, Longitude: 37.61, Heading: tt.heading, Speed: tt.speed}
+			distance := CalculateDistance(req.Latitude, req.Longitude, tt.incident.Latitude, tt.incident.Longitude)
+
+			result := approachIncident(tt.incident, req, distance)
+			if math.Abs(result.BoundaryDistance-tt.boundary) > 5 {
+				t.Errorf("approachIncident() boundary = %.1f, expected %.1f", result.BoundaryDistance, tt.boundary)
+			}
+			if math.Abs(result.Bearing-tt.bearing) > 0.5 {
+				t.Errorf("approachIncident() bearing = %.2f, expected %.2f", result.Bearing, tt.bearing)
+			}
+			if (result.TimeToEntry == nil) != (tt.eta == nil) {
+				t.Fatalf("approachIncident() time to entry = %v, expected %v", result.TimeToEntry, tt.eta)
+			}
+			if tt.eta != nil && math.Abs(*result.TimeToEntry-*tt.eta) > 1 {
+				t.Errorf("approachIncident() time to entry = %.1f, expected %.1f", *result.TimeToEntry, *tt.eta)
+			}
+		})
+	}
+}
+
+func TestEvaluateLocationApproaching(t *testing.T) {
+	req := models.LocationCheckRequest{Latitude: 55.75, Longitude: 37.61, UserID: "user"}
+	inside := models.Incident{Title: "Внутри", Latitude: 55.75, Longitude: 37.61, Radius: 500}
+	near := models.Incident{Title: "Рядом", Latitude: 55.76, Longitude: 37.61, Radius: 500} // граница ~610 м
+	far := models.Incident{Title: "Далеко", Latitude: 55.80, Longitude: 37.61, Radius: 500} // граница ~5 км
+	nearer := models.Incident{Title: "Ближе", Latitude: 55.755, Longitude: 37.62, Radius: 500}
+
+	options := evaluateOptions{warningDistance: 1000, insideConfidence: 0.5, possibleConfidence: 0.05}
+	response := evaluateLocation(req, []models.Incident{far, near, inside, nearer}, options)
+	if len(response.NearbyIncidents) != 1 || !response.HasDanger {
+		t.Fatalf("evaluateLocation() nearby = %d, has_danger = %v, expected 1 and true", len(response.NearbyIncidents), response.HasDanger)
+	}
+	if len(response.Approaching) != 2 {
+		t.Fatalf("evaluateLocation() approaching = %d, expected 2", len(response.Approaching))
+	}
+	if response.Approaching[0].Title != "Ближе" || response.Approaching[1].Title != "Рядом" {
+		t.Errorf("evaluateLocation() approaching order = %s, %s, expected Ближе, Рядом", response.Approaching[0].Title, response.Approaching[1].Title)
+	}
+	// Нулевая уверенность вычислена и должна попасть в ответ
+	body, err := json.Marshal(response.Approaching[0])
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(body), `"confidence":0`) {
+		t.Errorf("approaching zone JSON = %s, expected \"confidence\":0", body)
+	}
+
+	if response := evaluateLocation(req, []models.Incident{near}, evaluateOptions{}); len(response.Approaching) != 0 {
+		t.Errorf("evaluateLocation() with zero warning distance approaching = %d, expected 0", len(response.Approaching))
+	}
+}
+
+func TestInsideConfidence(t *testing.T) {
+	tests := []struct {
+		name     string
+		depth    float64
+		accuracy float64
+		min, max float64
+	}{
+		{name: "Без точности внутри", depth: 10, accuracy: 0, min: 1, max: 1},
+		{name: "Без точности снаружи", depth: -10, accuracy: 0, min: 0, max: 0},
+		{name: "На границе", depth: 0, accuracy: 200, min: 0.5, max: 0.5},
+		{name: "30 м снаружи при точности 200 м", depth: -30, accuracy: 200, min: 0.40, max: 0.42},
+		{name: "Глубоко внутри", depth: 500, accuracy: 50, min: 0.999, max: 1},
+		{name: "Далеко снаружи", depth: -500, accuracy: 50, min: 0, max: 0.001},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := insideConfidence(tt.depth, tt.accuracy); result < tt.min || result > tt.max {
+				t.Errorf("insideConfidence() = %.4f, expected between %.4f and %.4f", result, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestEvaluateLocationAccuracy(t *testing.T) {
+	float := func(v float64) *float64 { return &v }
+	// Граница зоны в ~30 м к северу от точки
+	zone := models.Incident{Title: "Зона", Latitude: 55.7551, Longitude: 37.61, Radius: 537}
+	options := evaluateOptions{warningDistance: 1000, insideConfidence: 0.5, possibleConfidence: 0.05}
+
+	tests := []struct {
+		name           string
+		accuracy       *float64
+		options        evaluateOptions
+		classification string
+		hasDanger      bool
+	}{
+		{name: "Без точности", options: options, classification: models.ZoneOutside},
+		{name: "Точность 200 м", accuracy: float(200), options: options, classification: models.ZonePossiblyInside},
+		{name: "Точность 10 м", accuracy: float(10), options: options, classification: models.ZoneOutside},
+		{name: "Пониженный порог", accuracy: float(200), options: evaluateOptions{warningDistance: 1000, insideConfidence: 0.3, possibleConfidence: 0.05}, classification: models.ZoneInside, hasDanger: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := models.LocationCheckRequest{Latitude: 55.75, Longitude: 37.61, UserID: "user", Accuracy: tt.accuracy}
+			response := evaluateLocation(req, []models.Incident{zone}, tt.options)
+
+			var zones []models.NearbyIncident
+			zones = append(zones, response.NearbyIncidents...)
+			for _, approaching := range response.Approaching {
+				zones = append(zones, approaching.NearbyIncident)
+			}
+			if len(zones) != 1 {
+				t.Fatalf("evaluateLocation() zones = %d, expected 1", len(zones))
+			}
+			if zones[0].Classification != tt.classification {
+				t.Errorf("evaluateLocation() classification = %s, expected %s", zones[0].Classification, tt.classification)
+			}
+			if response.HasDanger != tt.hasDanger {
+				t.Errorf("evaluateLocation() has_danger = %v, expected %v", response.HasDanger, tt.hasDanger)
+			}
+		})
+	}
+}
@@ -0,0 +1,355 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"geo_system_core/internal/auth"
+	"geo_system_core/internal/metrics"
+	"geo_system_core/internal/models"
+	"math"
+	"sort"
+	"time"
+)
+
+// ErrInvalidRoute — маршрут не задан, задан дважды или содержит недопустимые точки
+var ErrInvalidRoute = errors.New("invalid route")
+
+const (
+	maxRoutePoints = 10000
+	// routeBoundaryPrecision — точность определения точек входа и выхода для окружностей, м
+	routeBoundaryPrecision = 0.5
+)
+
+// CheckRoute находит все проходы маршрута через зоны инцидентов арендатора, действующих в момент выезда
+func (s *LocationService) CheckRoute(ctx context.Context, req models.RouteCheckRequest) (*models.RouteCheckResponse, error) {
+	route, err := routePoints(req)
+	if err != nil {
+		return nil, err
+	}
+	departure := time.Now()
+	if req.DepartureAt != nil {
+		departure = *req.DepartureAt
+	}
+
+	tenantID := auth.TenantFromContext(ctx)
+	start := time.Now()
+	source := "index"
+	incidents, ok := s.index.Active(tenantID, departure)
+	if !ok {
+		source = "database"
+		incidents, err = s.incidentRepo.GetActiveIncidentsAt(ctx, tenantID, departure)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get active incidents: %w", err)
+		}
+	}
+	metrics.LocationQueryDuration.WithLabelValues(source).Observe(time.Since(start).Seconds())
+
+	response := &models.RouteCheckResponse{Intersections: []models.RouteIntersection{}}
+	for i := 1; i < len(route); i++ {
+		response.RouteLength += CalculateDistance(route[i-1].Latitude, route[i-1].Longitude, route[i].Latitude, route[i].Longitude)
+	}
+	for i := range incidents {
+		response.Intersections = append(response.Intersections, routeIntersections(route, &incidents[i])...)
+	}
+	sort.SliceStable(response.Intersections, func(i, j int) bool {
+		return response.Intersections[i].EntryOffset < response.Intersections[j].EntryOffset
+	})
+	if len(response.Intersections) > 0 {
+		response.FirstIntersection = &response.Intersections[0]
+		response.HasDanger = true
+	}
+
+	return response, nil
+}
+
+// routePoints возвращает точки маршрута из запроса: из списка или из encoded polyline
+func routePoints(req models.RouteCheckRequest) ([]models.RoutePoint, error) {
+	if (len(req.Points) > 0) == (req.Polyline != "") {
+		return nil, fmt.Errorf("%w: exactly one of points and polyline is required", ErrInvalidRoute)
+	}
+
+	points := req.Points
+	if req.Polyline != "" {
+		precision := req.PolylinePrecision
+		if precision == 0 {
+			precision = 5
+		}
+		var err error
+		points, err = decodePolyline(req.Polyline, precision)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRoute, err)
+		}
+	}
+
+	if len(points) < 2 {
+		return nil, fmt.Errorf("%w: at least 2 points are required", ErrInvalidRoute)
+	}
+	if len(points) > maxRoutePoints {
+		return nil, fmt.Errorf("%w: at most %d points are allowed", ErrInvalidRoute, maxRoutePoints)
+	}
+	for i, point := range points {
+		if err := validateCoordinates(point.Latitude, point.Longitude); err != nil {
+			return nil, fmt.Errorf("%w: point %d: %v", ErrInvalidRoute, i, err)
+		}
+	}
+
+	return points, nil
+}
+
+// decodePolyline разбирает encoded polyline: последовательность разностей координат,
+// умноженных на 10^precision, в кодировке base64 по 5 бит со знаком в младшем бите
+func decodePolyline(encoded string, precision int) ([]models.RoutePoint, error) {
+	factor := math.Pow(10, float64(precision))
+	var points []models.RoutePoint
+	var lat, lng int64
+
+	for i := 0; i < len(encoded); {
+		var deltas [2]int64
+		for k := range deltas {
+			var result int64
+			shift := uint(0)
+			for {
+				if i >= len(encoded) {
+					return nil, fmt.Errorf("polyline is truncated")
+				}
+				b := int64(encoded[i]) - 63
+				i++
+				if b < 0 || b > 63 {
+					return nil, fmt.Errorf("invalid polyline character at %d", i-1)
+				}
+				if shift > 60 {
+					return nil, fmt.Errorf("polyline value is too long at %d", i-1)
+				}
+				result |= (b & 0x1f) << shift
+				shift += 5
+				if b < 0x20 {
+					break
+				}
+			}
+			if result&1 != 0 {
+				deltas[k] = ^(result >> 1)
+			} else {
+				deltas[k] = result >> 1
+			}
+		}
+		lat += deltas[0]
+		lng += deltas[1]
+		points = append(points, models.RoutePoint{Latitude: float64(lat) / factor, Longitude: float64(lng) / factor})
+	}
+
+	return points, nil
+}
+
+// routeIntersections находит проходы маршрута через зону инцидента. Для каждого отрезка маршрута
+// вычисляются параметры t всех пересечений с границей зоны; между соседними пересечениями точка
+// либо внутри, либо снаружи, что проверяется в середине промежутка. Поэтому зона любой ширины,
+// которую пересекает отрезок, не пропускается. Внутри отрезка координаты интерполируются линейно.
+func routeIntersections(route []models.RoutePoint, incident *models.Incident) []models.RouteIntersection {
+	bounds := incidentBounds(incident)
+	contains := func(point models.RoutePoint) bool {
+		if incident.Geometry != nil {
+			// Описанная окружность полигонов содержит их целиком, граница зоны — контуры полигонов
+			return pointInGeometry(incident.Geometry, point.Latitude, point.Longitude)
+		}
+		return CalculateDistance(point.Latitude, point.Longitude, incident.Latitude, incident.Longitude) <= incident.Radius
+	}
+
+	var (
+		intersections []models.RouteIntersection
+		current       *models.RouteIntersection
+		offset        float64
+	)
+	enter := func(point models.RoutePoint, at float64) {
+		current = &models.RouteIntersection{
+			IncidentID:  incident.ID,
+			Title:       incident.Title,
+			Severity:    incident.Severity,
+			Entry:       point,
+			EntryOffset: at,
+		}
+	}
+	exit := func(point models.RoutePoint, at float64) {
+		current.Exit = point
+		current.ExitOffset = at
+		current.DistanceInside = at - current.EntryOffset
+		intersections = append(intersections, *current)
+		current = nil
+	}
+
+	for i := 1; i < len(route); i++ {
+		a, b := route[i-1], route[i]
+		length := CalculateDistance(a.Latitude, a.Longitude, b.Latitude, b.Longitude)
+
+		// Вне габаритов зоны точек зоны нет: такой отрезок целиком снаружи
+		if _, _, ok := clipSegment(a, b, bounds); !ok {
+			if current != nil {
+				exit(a, offset)
+			}
+			offset += length
+			continue
+		}
+
+		var crossings []float64
+		if incident.Geometry != nil {
+			crossings = polygonCrossings(a, b, incident.Geometry)
+		} else {
+			crossings = circleCrossings(a, b, incident, bounds, length, contains)
+		}
+		ts := append(append([]float64{0}, crossings...), 1)
+		sort.Float64s(ts)
+
+		for k := 1; k < len(ts); k++ {
+			if ts[k] == ts[k-1] {
+				continue
+			}
+			inside := contains(interpolate(a, b, (ts[k-1]+ts[k])/2))
+			switch {
+			case inside && current == nil:
+				enter(interpolate(a, b, ts[k-1]), offset+ts[k-1]*length)
+			case !inside && current != nil:
+				exit(interpolate(a, b, ts[k-1]), offset+ts[k-1]*length)
+			}
+		}
+		offset += length
+	}
+	if current != nil {
+		exit(route[len(route)-1], offset)
+	}
+
+	return intersections
+}
+
+// incidentBounds возвращает габаритный прямоугольник зоны: контуров полигонов или окружности.
+// Прямоугольник окружности берется с запасом: в плоском приближении circleBounds она может чуть выходить за него.
+func incidentBounds(incident *models.Incident) models.BoundingBox {
+	if incident.Geometry != nil {
+		bounds := models.BoundingBox{MinLat: math.Inf(1), MinLng: math.Inf(1), MaxLat: math.Inf(-1), MaxLng: math.Inf(-1)}
+		for _, polygon := range incident.Geometry.Polygons {
+			for _, p := range polygon[0] {
+				bounds.MinLat = math.Min(bounds.MinLat, p.Lat())
+				bounds.MaxLat = math.Max(bounds.MaxLat, p.Lat())
+				bounds.MinLng = math.Min(bounds.MinLng, p.Lng())
+				bounds.MaxLng = math.Max(bounds.MaxLng, p.Lng())
+			}
+		}
+		return bounds
+	}
+	minLat, minLng, maxLat, maxLng := circleBounds(incident.Latitude, incident.Longitude, incident.Radius*1.01+1)
+	return models.BoundingBox{MinLat: minLat, MinLng: minLng, MaxLat: maxLat, MaxLng: maxLng}
+}
+
+// polygonCrossings возвращает параметры t пересечений отрезка ab со сторонами всех контуров геометрии.
+// Пересечения считаются в плоскости (долгота, широта), в которой pointInRing проверяет попадание в полигон.
+func polygonCrossings(a, b models.RoutePoint, g *models.Geometry) []float64 {
+	var crossings []float64
+	dx, dy := b.Longitude-a.Longitude, b.Latitude-a.Latitude
+	for _, polygon := range g.Polygons {
+		for _, ring := range polygon {
+			for i := 1; i < len(ring); i++ {
+				p, q := ring[i-1], ring[i]
+				ex, ey := q.Lng()-p.Lng(), q.Lat()-p.Lat()
+				denom := dx*ey - dy*ex
+				if denom == 0 {
+					continue // стороны, параллельные отрезку, учитываются через пересечения соседних сторон
+				}
+				wx, wy := p.Lng()-a.Longitude, p.Lat()-a.Latitude
+				t := (wx*ey - wy*ex) / denom
+				u := (wx*dy - wy*dx) / denom
+				if t >= 0 && t <= 1 && u >= 0 && u <= 1 {
+					crossings = append(crossings, t)
+				}
+			}
+		}
+	}
+	return crossings
+}
+
+// circleCrossings возвращает параметры t входа в окружность и выхода из нее на отрезке ab.
+// Расстояние до центра вдоль части отрезка внутри габаритов окружности сначала убывает, затем растет,
+// поэтому ближайшая к центру точка находится золотым сечением, а границы — делением пополам по обе стороны от нее.
+func circleCrossings(a, b models.RoutePoint, incident *models.Incident, bounds models.BoundingBox, length float64, contains func(models.RoutePoint) bool) []float64 {
+	t0, t1, _ := clipSegment(a, b, bounds)
+	distance := func(t float64) float64 {
+		p := interpolate(a, b, t)
+		return CalculateDistance(p.Latitude, p.Longitude, incident.Latitude, incident.Longitude)
+	}
+
+	const ratio = 0.6180339887498949 // (√5 − 1) / 2
+	lo, hi := t0, t1
+	m1, m2 := hi-ratio*(hi-lo), lo+ratio*(hi-lo)
+	d1, d2 := distance(m1), distance(m2)
+	for (hi-lo)*length > routeBoundaryPrecision {
+		if d1 <= d2 {
+			hi, m2, d2 = m2, m1, d1
+			m1 = hi - ratio*(hi-lo)
+			d1 = distance(m1)
+		} else {
+			lo, m1, d1 = m1, m2, d2
+			m2 = lo + ratio*(hi-lo)
+			d2 = distance(m2)
+		}
+	}
+	closest := (lo + hi) / 2
+	if distance(closest) > incident.Radius {
+		return nil
+	}
+
+	var crossings []float64
+	if distance(t0) > incident.Radius {
+		crossings = append(crossings, refineBoundary(a, b, t0, closest, false, length, contains))
+	}
+	if distance(t1) > incident.Radius {
+		crossings = append(crossings, refineBoundary(a, b, closest, t1, true, length, contains))
+	}
+	return crossings
+}
+
+// refineBoundary уточняет делением пополам параметр границы зоны между t0 (состояние inside0) и t1
+func refineBoundary(a, b models.RoutePoint, t0, t1 float64, inside0 bool, length float64, contains func(models.RoutePoint) bool) float64 {
+	for (t1-t0)*length > routeBoundaryPrecision {
+		mid := (t0 + t1) / 2
+		if contains(interpolate(a, b, mid)) == inside0 {
+			t0 = mid
+		} else {
+			t1 = mid
+		}
+	}
+	return (t0 + t1) / 2
+}
+
+func interpolate(a, b models.RoutePoint, t float64) models.RoutePoint {
+	return models.RoutePoint{
+		Latitude:  a.Latitude + (b.Latitude-a.Latitude)*t,
+		Longitude: a.Longitude + (b.Longitude-a.Longitude)*t,
+	}
+}
+
+// clipSegment возвращает параметры [t0, t1] части отрезка ab внутри прямоугольника (алгоритм Лианга — Барски);
+// ok == false, если отрезок прямоугольник не пересекает
+func clipSegment(a, b models.RoutePoint, bounds models.BoundingBox) (t0, t1 float64, ok bool) {
+	t0, t1 = 0, 1
+	dLat, dLng := b.Latitude-a.Latitude, b.Longitude-a.Longitude
+	edges := [4][2]float64{
+		{-dLat, a.Latitude - bounds.MinLat},
+		{dLat, bounds.MaxLat - a.Latitude},
+		{-dLng, a.Longitude - bounds.MinLng},
+		{dLng, bounds.MaxLng - a.Longitude},
+	}
+	for _, edge := range edges {
+		p, q := edge[0], edge[1]
+		if p == 0 {
+			if q < 0 {
+				return 0, 0, false // отрезок параллелен стороне и лежит снаружи
+			}
+			continue
+		}
+		r := q / p
+		if p < 0 {
+			t0 = math.Max(t0, r)
+		} else {
+			t1 = math.Min(t1, r)
+		}
+	}
+	return t0, t1, t0 <= t1
+}
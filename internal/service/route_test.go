@@ -0,0 +1,187 @@
+package service
+
+import (
+	"errors"
+	"geo_system_core/internal/models"
+	"math"
+	"testing"
+)
+
+func TestDecodePolyline(t *testing.T) {
+	// Пример из описания формата
+	points, err := decodePolyline("_p~iF~ps|U_ulLnnqC_mqNvxq`@", 5)
+	if err != nil {
+		t.Fatalf("decodePolyline() error = %v", err)
+	}
+
+	expected := []models.RoutePoint{{Latitude: 38.5, Longitude: -120.2}, {Latitude: 40.7, Longitude: -120.95}, {Latitude: 43.252, Longitude: -126.453}}
+	if len(points) != len(expected) {
+		t.Fatalf("decodePolyline() = %d points, expected %d", len(points), len(expected))
+	}
+	for i := range expected {
+		if math.Abs(points[i].Latitude-expected[i].Latitude) > 1e-9 || math.Abs(points[i].Longitude-expected[i].Longitude) > 1e-9 {
+			t.Errorf("decodePolyline()[%d] = %+v, expected %+v", i, points[i], expected[i])
+		}
+	}
+
+	for _, invalid := range []string{"_p~iF~ps|U_ulL", "_p~iF~ps|U_", "_p~iF ps|U"} {
+		if _, err := decodePolyline(invalid, 5); err == nil {
+			t.Errorf("decodePolyline(%q) error = nil, expected error", invalid)
+		}
+	}
+}
+
+func TestRoutePoints(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   models.RouteCheckRequest
+		valid bool
+	}{
+		{name: "Список точек", req: models.RouteCheckRequest{Points: []models.RoutePoint{{Latitude: 55, Longitude: 37}, {Latitude: 55.1, Longitude: 37.1}}}, valid: true},
+		{name: "Encoded polyline", req: models.RouteCheckRequest{Polyline: "_p~iF~ps|U_ulLnnqC"}, valid: true},
+		{name: "Маршрут не задан", req: models.RouteCheckRequest{}, valid: false},
+		{name: "Заданы оба варианта", req: models.RouteCheckRequest{Points: []models.RoutePoint{{}, {}}, Polyline: "_p~iF~ps|U_ulLnnqC"}, valid: false},
+		{name: "Одна точка", req: models.RouteCheckRequest{Points: []models.RoutePoint{{Latitude: 55, Longitude: 37}}}, valid: false},
+		{name: "Недопустимая широта", req: models.RouteCheckRequest{Points: []models.RoutePoint{{Latitude: 95, Longitude: 37}, {Latitude: 55, Longitude: 37}}}, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := routePoints(tt.req)
+			if (err == nil) != tt.valid {
+				t.Fatalf("routePoints() error = %v, expected valid: %v", err, tt.valid)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidRoute) {
+				t.Errorf("routePoints() error = %v, expected ErrInvalidRoute", err)
+			}
+		})
+	}
+}
+
+func TestRouteIntersections(t *testing.T) {
+	incident := &models.Incident{Title: "Пожар", Severity: "high", Latitude: 55.75, Longitude: 37.61, Radius: 1000}
+	// Точки маршрута на меридиане центра зоны
+	meridian := func(lat float64) models.RoutePoint { return models.RoutePoint{Latitude: lat, Longitude: 37.61} }
+
+	tests := []struct {
+		name        string
+		route       []models.RoutePoint
+		passes      int
+		entryOffset float64
+		inside      float64
+	}{
+		{name: "Маршрут через центр зоны", route: []models.RoutePoint{meridian(55.70), meridian(55.80)}, passes: 1, entryOffset: CalculateDistance(55.70, 37.61, 55.75, 37.61) - 1000, inside: 2000},
+		{name: "Маршрут мимо зоны", route: []models.RoutePoint{{Latitude: 55.70, Longitude: 37.70}, {Latitude: 55.80, Longitude: 37.70}}, passes: 0},
+		{name: "Маршрут начинается внутри", route: []models.RoutePoint{meridian(55.75), meridian(55.80)}, passes: 1, entryOffset: 0, inside: 1000},
+		{name: "Вход, выход и повторный вход", route: []models.RoutePoint{meridian(55.70), meridian(55.80), meridian(55.70)}, passes: 2, entryOffset: CalculateDistance(55.70, 37.61, 55.75, 37.61) - 1000, inside: 2000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			intersections := routeIntersections(tt.route, incident)
+			if len(intersections) != tt.passes {
+				t.Fatalf("routeIntersections() = %d passes, expected %d", len(intersections), tt.passes)
+			}
+			if tt.passes == 0 {
+				return
+			}
+			first := intersections[0]
+			if math.Abs(first.EntryOffset-tt.entryOffset) > 1 {
+				t.Errorf("routeIntersections() entry offset = %.1f, expected %.1f", first.EntryOffset, tt.entryOffset)
+			}
+			if math.Abs(first.DistanceInside-tt.inside) > 2 {
+				t.Errorf("routeIntersections() distance inside = %.1f, expected %.1f", first.DistanceInside, tt.inside)
+			}
+		})
+	}
+}
+
+func TestRouteIntersectionsLongSegment(t *testing.T) {
+	// Отрезок через весь континент проходит через маленькую зону посередине
+	a, b := models.RoutePoint{Latitude: 40, Longitude: -9}, models.RoutePoint{Latitude: 43, Longitude: 132}
+	center := interpolate(a, b, 0.5)
+	small := &models.Incident{Latitude: center.Latitude, Longitude: center.Longitude, Radius: 10}
+	aside := &models.Incident{Latitude: center.Latitude + 0.001, Longitude: center.Longitude, Radius: 10}
+
+	if intersections := routeIntersections([]models.RoutePoint{a, b}, small); len(intersections) != 1 {
+		t.Fatalf("routeIntersections() = %d passes, expected 1", len(intersections))
+	}
+	if intersections := routeIntersections([]models.RoutePoint{a, b}, aside); len(intersections) != 0 {
+		t.Errorf("routeIntersections() = %d passes, expected 0", len(intersections))
+	}
+}
+
+func TestRouteIntersectionsThinZone(t *testing.T) {
+	// Полоса 2 км в длину и около 1 м в ширину: описанный радиус 1 км, то есть
+	// шаг прежней проверки точками был бы 50 м и полоса оказалась бы между точками
+	strip := `[[37.60,55.749995],[37.632,55.749995],[37.632,55.750005],[37.60,55.750005],[37.60,55.749995]]`
+	square := `[[37.70,55.74],[37.72,55.74],[37.72,55.76],[37.70,55.76],[37.70,55.74]]`
+	newIncident := func(geometry string) *models.Incident {
+		incident := &models.Incident{Title: "Разлив", Severity: "high", Geometry: mustGeometry(t, geometry)}
+		incident.Latitude, incident.Longitude, incident.Radius = geometryCircle(incident.Geometry)
+		return incident
+	}
+	crossing := func(lng float64) []models.RoutePoint {
+		return []models.RoutePoint{{Latitude: 55.70, Longitude: lng}, {Latitude: 55.80, Longitude: lng}}
+	}
+
+	tests := []struct {
+		name     string
+		incident *models.Incident
+		route    []models.RoutePoint
+		passes   int
+		inside   float64
+	}{
+		{name: "Маршрут пересекает узкую полосу", incident: newIncident(`{"type":"Polygon","coordinates":[` + strip + `]}`), route: crossing(37.6123), passes: 1, inside: 1.1},
+		{name: "Маршрут проходит мимо полосы", incident: newIncident(`{"type":"Polygon","coordinates":[` + strip + `]}`), route: crossing(37.64), passes: 0},
+		{name: "Узкий рукав мультиполигона", incident: newIncident(`{"type":"MultiPolygon","coordinates":[[` + square + `],[` + strip + `]]}`), route: crossing(37.6111), passes: 1, inside: 1.1},
+		{name: "Оба полигона мультиполигона", incident: newIncident(`{"type":"MultiPolygon","coordinates":[[` + square + `],[` + strip + `]]}`),
+			route: []models.RoutePoint{{Latitude: 55.70, Longitude: 37.61}, {Latitude: 55.80, Longitude: 37.61}, {Latitude: 55.80, Longitude: 37.71}, {Latitude: 55.70, Longitude: 37.71}}, passes: 2},
+		{name: "Узкая окружность", incident: &models.Incident{Latitude: 55.75, Longitude: 37.6123, Radius: 0.3}, route: crossing(37.6123), passes: 1, inside: 0.6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			intersections := routeIntersections(tt.route, tt.incident)
+			if len(intersections) != tt.passes {
+				t.Fatalf("routeIntersections() = %d passes, expected %d", len(intersections), tt.passes)
+			}
+			if tt.inside > 0 && math.Abs(intersections[0].DistanceInside-tt.inside) > 0.5 {
+				t.Errorf("routeIntersections() distance inside = %.2f, expected %.2f", intersections[0].DistanceInside, tt.inside)
+			}
+			for i := 1; i < len(intersections); i++ {
+				if intersections[i].EntryOffset < intersections[i-1].ExitOffset {
+					t.Errorf("routeIntersections() passes are not ordered along the route: %+v", intersections)
+				}
+			}
+		})
+	}
+}
+
+func TestClipSegment(t *testing.T) {
+	bounds := models.BoundingBox{MinLat: 0, MinLng: 0, MaxLat: 1, MaxLng: 1}
+
+	tests := []struct {
+		name   string
+		a, b   models.RoutePoint
+		t0, t1 float64
+		ok     bool
+	}{
+		{name: "Отрезок внутри", a: models.RoutePoint{Latitude: 0.2, Longitude: 0.2}, b: models.RoutePoint{Latitude: 0.8, Longitude: 0.8}, t0: 0, t1: 1, ok: true},
+		{name: "Отрезок насквозь", a: models.RoutePoint{Latitude: 0.5, Longitude: -1}, b: models.RoutePoint{Latitude: 0.5, Longitude: 2}, t0: 1.0 / 3, t1: 2.0 / 3, ok: true},
+		{name: "Отрезок снаружи", a: models.RoutePoint{Latitude: 2, Longitude: -1}, b: models.RoutePoint{Latitude: 2, Longitude: 2}, ok: false},
+		{name: "Отрезок мимо угла", a: models.RoutePoint{Latitude: 1.5, Longitude: -1}, b: models.RoutePoint{Latitude: -1, Longitude: -0.2}, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t0, t1, ok := clipSegment(tt.a, tt.b, bounds)
+			if ok != tt.ok {
+				t.Fatalf("clipSegment() ok = %v, expected %v", ok, tt.ok)
+			}
+			if ok && (math.Abs(t0-tt.t0) > 1e-9 || math.Abs(t1-tt.t1) > 1e-9) {
+				t.Errorf("clipSegment() = [%.4f, %.4f], expected [%.4f, %.4f]", t0, t1, tt.t0, tt.t1)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"geo_system_core/internal/auth"
 	"geo_system_core/internal/models"
 	"geo_system_core/internal/repository/postgres"
 )
@@ -18,8 +19,9 @@ func NewStatsService(locationRepo *postgres.LocationRepository, timeWindowMinute
 	}
 }
 
+// GetZoneStats считает статистику по зонам арендатора из контекста запроса
 func (s *StatsService) GetZoneStats(ctx context.Context) (*models.StatsResponse, error) {
-	stats, err := s.locationRepo.GetZoneStats(ctx, s.timeWindow)
+	stats, err := s.locationRepo.GetZoneStats(ctx, auth.TenantFromContext(ctx), s.timeWindow)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"geo_system_core/internal/auth"
+	"geo_system_core/internal/models"
+	"geo_system_core/internal/repository/postgres"
+)
+
+// ErrInvalidSubscription — ошибка валидации фильтров подписки
+var ErrInvalidSubscription = errors.New("invalid subscription")
+
+// SubscriptionService управляет подписками арендатора из контекста запроса
+type SubscriptionService struct {
+	repo *postgres.SubscriptionRepository
+}
+
+func NewSubscriptionService(repo *postgres.SubscriptionRepository) *SubscriptionService {
+	return &SubscriptionService{repo: repo}
+}
+
+func (s *SubscriptionService) Create(ctx context.Context, req models.CreateSubscriptionRequest) (*models.WebhookSubscription, error) {
+	sub := &models.WebhookSubscription{
+		TenantID:    auth.TenantFromContext(ctx),
+		URL:         req.URL,
+		Secret:      req.Secret,
+		MinSeverity: req.MinSeverity,
+		BBox:        req.BBox,
+		Geometry:    req.Geometry,
+		EventTypes:  req.EventTypes,
+	}
+	if sub.MinSeverity == "" {
+		sub.MinSeverity = "low"
+	}
+	if sub.EventTypes == nil {
+		sub.EventTypes = []string{}
+	}
+	if err := validateSubscription(sub); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Create(ctx, sub)
+}
+
+func (s *SubscriptionService) GetByID(ctx context.Context, id string) (*models.WebhookSubscription, error) {
+	uuid, err := parseUUID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, auth.TenantFromContext(ctx), uuid)
+}
+
+func (s *SubscriptionService) List(ctx context.Context) ([]models.WebhookSubscription, error) {
+	return s.repo.List(ctx, auth.TenantFromContext(ctx))
+}
+
+// Update изменяет подписку. Передача bbox заменяет область-полигон и наоборот.
+func (s *SubscriptionService) Update(ctx context.Context, id string, req models.UpdateSubscriptionRequest) (*models.WebhookSubscription, error) {
+	uuid, err := parseUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := s.repo.GetByID(ctx, auth.TenantFromContext(ctx), uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL != nil {
+		sub.URL = *req.URL
+	}
+	if req.Secret != nil {
+		sub.Secret = *req.Secret
+	}
+	if req.MinSeverity != nil {
+		sub.MinSeverity = *req.MinSeverity
+	}
+	if req.BBox != nil {
+		sub.BBox, sub.Geometry = req.BBox, nil
+	}
+	if req.Geometry != nil {
+		sub.BBox, sub.Geometry = nil, req.Geometry
+	}
+	if req.EventTypes != nil {
+		sub.EventTypes = *req.EventTypes
+	}
+	if req.IsActive != nil {
+		sub.IsActive = *req.IsActive
+	}
+	if err := validateSubscription(sub); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Update(ctx, sub)
+}
+
+func (s *SubscriptionService) Delete(ctx context.Context, id string) error {
+	uuid, err := parseUUID(id)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, auth.TenantFromContext(ctx), uuid)
+}
+
+func validateSubscription(sub *models.WebhookSubscription) error {
+	if sub.BBox != nil && sub.Geometry != nil {
+		return fmt.Errorf("%w: only one of bbox and geometry can be set", ErrInvalidSubscription)
+	}
+	if sub.BBox != nil {
+		if err := validateBoundingBox(*sub.BBox); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSubscription, err)
+		}
+	}
+	if sub.Geometry != nil {
+		if err := validateGeometry(sub.Geometry); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSubscription, err)
+		}
+	}
+	return nil
+}
+
+func validateBoundingBox(b models.BoundingBox) error {
+	if err := validateCoordinates(b.MinLat, b.MinLng); err != nil {
+		return err
+	}
+	if err := validateCoordinates(b.MaxLat, b.MaxLng); err != nil {
+		return err
+	}
+	if b.MinLat > b.MaxLat || b.MinLng > b.MaxLng {
+		return fmt.Errorf("bbox minimum must not exceed maximum")
+	}
+	return nil
+}
+
+var severityRanks = map[string]int{"low": 1, "medium": 2, "high": 3, "critical": 4}
+
+// subscriptionMatches проверяет, подходит ли событие под фильтры подписки.
+// Подписка получает только события своего арендатора.
+// Важность берется из зоны или инцидента события, точка — из координат события.
+func subscriptionMatches(sub *models.WebhookSubscription, payload *models.WebhookPayload) bool {
+	if sub.TenantID != payloadTenant(payload) {
+		return false
+	}
+	if len(sub.EventTypes) > 0 {
+		matched := false
+		for _, eventType := range sub.EventTypes {
+			if eventType == payload.Event {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	severity := ""
+	if payload.Zone != nil {
+		severity = payload.Zone.Severity
+	} else if payload.Incident != nil {
+		severity = payload.Incident.Severity
+	}
+	if severity != "" && severityRanks[severity] < severityRanks[sub.MinSeverity] {
+		return false
+	}
+
+	if sub.BBox != nil && !sub.BBox.Contains(payload.Latitude, payload.Longitude) {
+		return false
+	}
+	if sub.Geometry != nil && !pointInGeometry(sub.Geometry, payload.Latitude, payload.Longitude) {
+		return false
+	}
+
+	return true
+}
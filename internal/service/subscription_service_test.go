@@ -0,0 +1,111 @@
+package service
+
+import (
+	"geo_system_core/internal/models"
+	"testing"
+)
+
+func TestSubscriptionMatches(t *testing.T) {
+	moscow := &models.BoundingBox{MinLat: 55.5, MinLng: 37.3, MaxLat: 56.0, MaxLng: 37.9}
+	square := &models.Geometry{
+		Type: models.GeometryPolygon,
+		Polygons: []models.Polygon{{
+			{{37.0, 55.0}, {38.0, 55.0}, {38.0, 56.0}, {37.0, 56.0}, {37.0, 55.0}},
+		}},
+	}
+	enter := &models.WebhookPayload{
+		Event:     models.EventZoneEnter,
+		Latitude:  55.7558,
+		Longitude: 37.6173,
+		Zone:      &models.NearbyIncident{Severity: "high"},
+	}
+	expired := &models.WebhookPayload{
+		Event:     models.EventIncidentExpired,
+		Latitude:  59.9343,
+		Longitude: 30.3351,
+		Incident:  &models.Incident{Severity: "low"},
+	}
+	northEnter := &models.WebhookPayload{
+		Event:     models.EventZoneEnter,
+		TenantID:  "north",
+		Latitude:  55.7558,
+		Longitude: 37.6173,
+		Zone:      &models.NearbyIncident{Severity: "high"},
+	}
+
+	tests := []struct {
+		name     string
+		sub      models.WebhookSubscription
+		payload  *models.WebhookPayload
+		expected bool
+	}{
+		{
+			name:     "Подписка без фильтров получает все события",
+			sub:      models.WebhookSubscription{TenantID: models.DefaultTenant, MinSeverity: "low"},
+			payload:  expired,
+			expected: true,
+		},
+		{
+			name:     "Тип события не подходит",
+			sub:      models.WebhookSubscription{TenantID: models.DefaultTenant, MinSeverity: "low", EventTypes: []string{models.EventZoneExit}},
+			payload:  enter,
+			expected: false,
+		},
+		{
+			name:     "Важность ниже минимальной",
+			sub:      models.WebhookSubscription{TenantID: models.DefaultTenant, MinSeverity: "critical"},
+			payload:  enter,
+			expected: false,
+		},
+		{
+			name:     "Важность инцидента для incident.expired",
+			sub:      models.WebhookSubscription{TenantID: models.DefaultTenant, MinSeverity: "medium"},
+			payload:  expired,
+			expected: false,
+		},
+		{
+			name:     "Точка внутри bbox",
+			sub:      models.WebhookSubscription{TenantID: models.DefaultTenant, MinSeverity: "high", BBox: moscow},
+			payload:  enter,
+			expected: true,
+		},
+		{
+			name:     "Точка вне bbox",
+			sub:      models.WebhookSubscription{TenantID: models.DefaultTenant, MinSeverity: "low", BBox: moscow},
+			payload:  expired,
+			expected: false,
+		},
+		{
+			name:     "Точка внутри полигона",
+			sub:      models.WebhookSubscription{TenantID: models.DefaultTenant, MinSeverity: "low", Geometry: square},
+			payload:  enter,
+			expected: true,
+		},
+		{
+			name:     "Событие другого арендатора",
+			sub:      models.WebhookSubscription{TenantID: models.DefaultTenant, MinSeverity: "low"},
+			payload:  northEnter,
+			expected: false,
+		},
+		{
+			name:     "Событие своего арендатора",
+			sub:      models.WebhookSubscription{TenantID: "north", MinSeverity: "low"},
+			payload:  northEnter,
+			expected: true,
+		},
+		{
+			name:     "Событие без арендатора относится к default",
+			sub:      models.WebhookSubscription{TenantID: "north", MinSeverity: "low"},
+			payload:  enter,
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := subscriptionMatches(&tt.sub, tt.payload); got != tt.expected {
+				t.Errorf("subscriptionMatches() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
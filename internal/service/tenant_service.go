@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"geo_system_core/internal/models"
+	"geo_system_core/internal/repository/postgres"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const (
+	// tenantsRefresh — как часто перечитывать список арендаторов для проверки заголовка X-Tenant-ID
+	tenantsRefresh = time.Minute
+	// tenantsMissRefresh ограничивает перечитывание списка из-за неизвестных идентификаторов
+	tenantsMissRefresh = 5 * time.Second
+)
+
+// ErrInvalidTenant — ошибка валидации идентификатора арендатора
+var ErrInvalidTenant = errors.New("invalid tenant")
+
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+type TenantService struct {
+	repo *postgres.TenantRepository
+
+	mu       sync.Mutex
+	known    map[string]bool
+	loadedAt time.Time
+}
+
+func NewTenantService(repo *postgres.TenantRepository) *TenantService {
+	return &TenantService{repo: repo}
+}
+
+func (s *TenantService) Create(ctx context.Context, req models.CreateTenantRequest) (*models.Tenant, error) {
+	if !tenantIDPattern.MatchString(req.ID) {
+		return nil, fmt.Errorf("%w: id must contain only lowercase letters, digits and hyphens", ErrInvalidTenant)
+	}
+
+	tenant, err := s.repo.Create(ctx, models.Tenant{ID: req.ID, Name: req.Name})
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.known != nil {
+		s.known[tenant.ID] = true
+	}
+	s.mu.Unlock()
+
+	return tenant, nil
+}
+
+func (s *TenantService) List(ctx context.Context) ([]models.Tenant, error) {
+	return s.repo.List(ctx)
+}
+
+// Exists проверяет арендатора по списку, который перечитывается не чаще tenantsRefresh.
+// Неизвестный идентификатор перечитывает список не чаще tenantsMissRefresh, чтобы арендатор,
+// созданный на другой реплике, был доступен почти сразу.
+func (s *TenantService) Exists(ctx context.Context, tenantID string) (bool, error) {
+	if tenantID == models.DefaultTenant {
+		return true, nil
+	}
+	if !tenantIDPattern.MatchString(tenantID) {
+		return false, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	age := time.Since(s.loadedAt)
+	if s.known[tenantID] && age < tenantsRefresh {
+		return true, nil
+	}
+	if !s.known[tenantID] && age < tenantsMissRefresh {
+		return false, nil
+	}
+
+	tenants, err := s.repo.List(ctx)
+	if err != nil {
+		return false, err
+	}
+	s.known = make(map[string]bool, len(tenants))
+	for _, tenant := range tenants {
+		s.known[tenant.ID] = true
+	}
+	s.loadedAt = time.Now()
+
+	return s.known[tenantID], nil
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"geo_system_core/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TrackingService ведет сессии непрерывного отслеживания: клиент присылает позиции по одному
+// соединению, а сервер сообщает об изменении набора опасных зон и о новых инцидентах рядом
+type TrackingService struct {
+	location       *LocationService
+	events         *IncidentEvents
+	nearbyDistance float64
+}
+
+// NewTrackingService создает сервис; nearbyDistance — на каком расстоянии в метрах от границы зоны
+// клиент узнает о новом инциденте
+func NewTrackingService(location *LocationService, events *IncidentEvents, nearbyDistance float64) *TrackingService {
+	return &TrackingService{location: location, events: events, nearbyDistance: nearbyDistance}
+}
+
+// TrackingSession — состояние одного соединения. Методы вызываются из одной горутины.
+type TrackingSession struct {
+	service  *TrackingService
+	userID   string
+	sub      *EventSubscription
+	position *models.TrackingPosition
+	zones    map[uuid.UUID]string
+}
+
+// Start открывает сессию пользователя арендатора из контекста запроса
+func (s *TrackingService) Start(ctx context.Context, userID string) (*TrackingSession, error) {
+	sub, err := s.events.Subscribe(ctx, models.IncidentEventFilter{}, "")
+	if err != nil {
+		return nil, err
+	}
+	return &TrackingSession{service: s, userID: userID, sub: sub}, nil
+}
+
+// Events — изменения инцидентов арендатора; канал закрывается, если сессия не успевает их обрабатывать
+func (t *TrackingSession) Events() <-chan models.IncidentEvent {
+	return t.sub.Events()
+}
+
+// Done закрывается при остановке сервиса
+func (t *TrackingSession) Done() <-chan struct{} {
+	return t.sub.Done()
+}
+
+func (t *TrackingSession) Close() {
+	t.sub.Close()
+}
+
+// Position проверяет новую позицию так же, как POST /location/check (с записью проверки и событиями зон),
+// и возвращает состояние, если набор зон изменился или позиция первая
+func (t *TrackingSession) Position(ctx context.Context, position models.TrackingPosition) (*models.TrackingMessage, error) {
+	state, err := t.service.location.CheckLocation(ctx, t.request(position))
+	if err != nil {
+		return nil, err
+	}
+
+	first := t.position == nil
+	t.position = &position
+	if !t.updateZones(state) && !first {
+		return nil, nil
+	}
+	return &models.TrackingMessage{Type: models.TrackingMessageState, State: state, Timestamp: time.Now()}, nil
+}
+
+// Incident обрабатывает изменение инцидента: сообщает о новом инциденте рядом с последней позицией
+// и о смене набора зон, если изменение его затронуло. До первой позиции ничего не возвращает.
+func (t *TrackingSession) Incident(ctx context.Context, event models.IncidentEvent) ([]models.TrackingMessage, error) {
+	if t.position == nil {
+		return nil, nil
+	}
+	now := time.Now()
+	req := t.request(*t.position)
+
+	var messages []models.TrackingMessage
+	incident := event.Incident
+	if event.Event == models.EventIncidentCreated && activeAt(&incident, now) {
+		distance := CalculateDistance(req.Latitude, req.Longitude, incident.Latitude, incident.Longitude)
+		if distance-incident.Radius <= t.service.nearbyDistance {
+			messages = append(messages, models.TrackingMessage{
+				Type:      models.TrackingMessageIncident,
+				Incident:  nearbyIncident(&incident, distance),
+				Timestamp: now,
+			})
+		}
+	}
+
+	state, err := t.service.location.Evaluate(ctx, req)
+	if err != nil {
+		return messages, err
+	}
+	state = applyIncidentEvent(state, req, event, now, t.service.location.options(req))
+	if t.updateZones(state) {
+		messages = append(messages, models.TrackingMessage{Type: models.TrackingMessageState, State: state, Timestamp: now})
+	}
+
+	return messages, nil
+}
+
+func (t *TrackingSession) request(position models.TrackingPosition) models.LocationCheckRequest {
+	return models.LocationCheckRequest{
+		Latitude:  position.Latitude,
+		Longitude: position.Longitude,
+		UserID:    t.userID,
+		Accuracy:  position.Accuracy,
+	}
+}
+
+// updateZones запоминает зоны из state и сообщает, изменился ли их набор или классификация
+func (t *TrackingSession) updateZones(state *models.LocationCheckResponse) bool {
+	zones := make(map[uuid.UUID]string, len(state.NearbyIncidents))
+	for _, zone := range state.NearbyIncidents {
+		zones[zone.ID] = zone.Classification
+	}
+
+	changed := len(zones) != len(t.zones)
+	for id, classification := range zones {
+		if previous, ok := t.zones[id]; !ok || previous != classification {
+			changed = true
+		}
+	}
+	t.zones = zones
+	return changed
+}
+
+// applyIncidentEvent учитывает в результате проверки инцидент из события: индекс этой реплики
+// получает изменения других реплик только при следующем обновлении
+func applyIncidentEvent(state *models.LocationCheckResponse, req models.LocationCheckRequest, event models.IncidentEvent, now time.Time, options evaluateOptions) *models.LocationCheckResponse {
+	result := &models.LocationCheckResponse{}
+	for _, zone := range state.NearbyIncidents {
+		if zone.ID != event.Incident.ID {
+			result.NearbyIncidents = append(result.NearbyIncidents, zone)
+		}
+	}
+
+	incident := event.Incident
+	live := event.Event == models.EventIncidentCreated || event.Event == models.EventIncidentUpdated
+	if live && activeAt(&incident, now) {
+		options.warningDistance = 0
+		result.NearbyIncidents = append(result.NearbyIncidents, evaluateLocation(req, []models.Incident{incident}, options).NearbyIncidents...)
+	}
+
+	result.HasDanger = len(insideZones(result.NearbyIncidents)) > 0
+	return result
+}
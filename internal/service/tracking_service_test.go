@@ -0,0 +1,72 @@
+package service
+
+import (
+	"geo_system_core/internal/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestApplyIncidentEvent(t *testing.T) {
+	now := time.Now()
+	req := models.LocationCheckRequest{Latitude: 55.75, Longitude: 37.61, UserID: "user"}
+	covering := models.Incident{ID: uuid.New(), Latitude: 55.75, Longitude: 37.61, Radius: 500, Severity: "high", Status: "active", IsActive: true}
+	known := models.NearbyIncident{ID: uuid.New(), Classification: models.ZoneInside}
+	options := evaluateOptions{insideConfidence: 0.5, possibleConfidence: 0.05}
+	future := now.Add(time.Hour)
+	scheduled := covering
+	scheduled.StartsAt = &future
+
+	tests := []struct {
+		name     string
+		state    []models.NearbyIncident
+		event    models.IncidentEvent
+		expected int
+	}{
+		{name: "Новая зона накрывает позицию", event: models.IncidentEvent{Event: models.EventIncidentCreated, Incident: covering}, expected: 1},
+		{name: "Зона уже учтена индексом", state: []models.NearbyIncident{{ID: covering.ID, Classification: models.ZoneInside}}, event: models.IncidentEvent{Event: models.EventIncidentUpdated, Incident: covering}, expected: 1},
+		{name: "Зона удалена", state: []models.NearbyIncident{{ID: covering.ID, Classification: models.ZoneInside}, known}, event: models.IncidentEvent{Event: models.EventIncidentDeleted, Incident: covering}, expected: 1},
+		{name: "Зона завершена", state: []models.NearbyIncident{{ID: covering.ID, Classification: models.ZoneInside}}, event: models.IncidentEvent{Event: models.EventIncidentResolved, Incident: covering}, expected: 0},
+		{name: "Зона начнет действовать позже", event: models.IncidentEvent{Event: models.EventIncidentCreated, Incident: scheduled}, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &models.LocationCheckResponse{NearbyIncidents: tt.state, HasDanger: len(tt.state) > 0}
+			result := applyIncidentEvent(state, req, tt.event, now, options)
+			if len(result.NearbyIncidents) != tt.expected {
+				t.Errorf("applyIncidentEvent() zones = %d, expected %d", len(result.NearbyIncidents), tt.expected)
+			}
+			if result.HasDanger != (tt.expected > 0) {
+				t.Errorf("applyIncidentEvent() has_danger = %v, expected %v", result.HasDanger, tt.expected > 0)
+			}
+		})
+	}
+}
+
+func TestTrackingSessionUpdateZones(t *testing.T) {
+	a, b := models.NearbyIncident{ID: uuid.New(), Classification: models.ZoneInside}, models.NearbyIncident{ID: uuid.New(), Classification: models.ZoneInside}
+	possible := a
+	possible.Classification = models.ZonePossiblyInside
+	session := &TrackingSession{}
+
+	steps := []struct {
+		name    string
+		zones   []models.NearbyIncident
+		changed bool
+	}{
+		{name: "Вне зон", zones: nil, changed: false},
+		{name: "Вход в зону", zones: []models.NearbyIncident{a}, changed: true},
+		{name: "Та же зона", zones: []models.NearbyIncident{a}, changed: false},
+		{name: "Точность ухудшилась", zones: []models.NearbyIncident{possible}, changed: true},
+		{name: "Смена зоны", zones: []models.NearbyIncident{b}, changed: true},
+		{name: "Выход из зоны", zones: nil, changed: true},
+	}
+
+	for _, step := range steps {
+		if changed := session.updateZones(&models.LocationCheckResponse{NearbyIncidents: step.zones}); changed != step.changed {
+			t.Errorf("%s: updateZones() = %v, expected %v", step.name, changed, step.changed)
+		}
+	}
+}
@@ -6,23 +6,54 @@ import (
 	"encoding/json"
 	"fmt"
 	"geo_system_core/internal/config"
+	"geo_system_core/internal/metrics"
 	"geo_system_core/internal/models"
+	"geo_system_core/internal/repository/postgres"
 	"geo_system_core/internal/repository/redis"
+	"geo_system_core/internal/signing"
 	"io"
+	"log"
 	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/google/uuid"
 )
 
+// webhookTarget — адрес доставки события: подписка из БД или адрес из WEBHOOK_URL (subscriptionID == uuid.Nil)
+type webhookTarget struct {
+	subscriptionID uuid.UUID
+	url            string
+	secrets        []string
+}
+
 type WebhookService struct {
-	queueRepo *redis.QueueRepository
-	config    *config.WebhookConfig
-	client    *http.Client
+	queueRepo        *redis.QueueRepository
+	deadLetterRepo   *redis.DeadLetterRepository
+	subscriptionRepo *postgres.SubscriptionRepository
+	config           *config.WebhookConfig
+	client           *http.Client
+
+	mu            sync.Mutex
+	subscriptions []models.WebhookSubscription
+	loadedAt      time.Time
+
+	heartbeat atomic.Int64 // время последнего прохода цикла воркера или попытки доставки, UnixNano
 }
 
-func NewWebhookService(queueRepo *redis.QueueRepository, cfg *config.WebhookConfig) *WebhookService {
+func NewWebhookService(
+	queueRepo *redis.QueueRepository,
+	deadLetterRepo *redis.DeadLetterRepository,
+	subscriptionRepo *postgres.SubscriptionRepository,
+	cfg *config.WebhookConfig,
+) *WebhookService {
 	return &WebhookService{
-		queueRepo: queueRepo,
-		config:    cfg,
+		queueRepo:        queueRepo,
+		deadLetterRepo:   deadLetterRepo,
+		subscriptionRepo: subscriptionRepo,
+		config:           cfg,
 		client: &http.Client{
 			Timeout: cfg.Timeout,
 		},
@@ -35,25 +66,179 @@ func (s *WebhookService) StartWorker(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		default:
-			payload, err := s.queueRepo.DequeueWebhook(ctx)
+			s.beat()
+			job, err := s.queueRepo.DequeueWebhook(ctx, s.config.VisibilityTimeout)
 			if err != nil {
 				time.Sleep(1 * time.Second)
 				continue
 			}
-			if payload == nil {
+			if job == nil {
 				time.Sleep(1 * time.Second)
 				continue
 			}
 
+			if job.SubscriptionID != nil {
+				s.redeliver(ctx, *job.SubscriptionID, &job.Payload)
+			} else {
+				s.dispatch(ctx, &job.Payload)
+			}
+
+			// Подтверждаем после доставки всем получателям или сохранения в dead-letter;
+			// без подтверждения событие вернется в очередь по истечении VisibilityTimeout.
+			// Доставка, прерванная остановкой сервиса, не подтверждается и будет повторена.
+			if ctx.Err() != nil {
+				return
+			}
+			if err := s.queueRepo.AckWebhook(ctx, job.Raw); err != nil {
+				log.Printf("webhook worker: %v", err)
+			}
+		}
+	}
+}
+
+// LastHeartbeat возвращает время последней активности воркера; нулевое — воркер не запускался
+func (s *WebhookService) LastHeartbeat() time.Time {
+	nanos := s.heartbeat.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+func (s *WebhookService) beat() {
+	s.heartbeat.Store(time.Now().UnixNano())
+}
+
+// StartReaper периодически возвращает в очередь события, которые взял упавший
+// или остановленный воркер и не подтвердил за VisibilityTimeout
+func (s *WebhookService) StartReaper(ctx context.Context) {
+	ticker := time.NewTicker(s.config.ReaperInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			requeued, err := s.queueRepo.RequeueStaleWebhooks(ctx, s.config.VisibilityTimeout)
+			if err != nil {
+				log.Printf("webhook reaper: %v", err)
+			}
+			if requeued > 0 {
+				log.Printf("webhook reaper: requeued %d stale deliveries", requeued)
+			}
+
+			// Глубина очереди обновляется с тем же периодом
+			if queued, processing, err := s.queueRepo.WebhookQueueLength(ctx); err == nil {
+				metrics.WebhookQueueDepth.WithLabelValues("queued").Set(float64(queued))
+				metrics.WebhookQueueDepth.WithLabelValues("processing").Set(float64(processing))
+			}
+		}
+	}
+}
+
+// dispatch рассылает событие всем подходящим получателям параллельно,
+// чтобы медленный подписчик не задерживал остальных
+func (s *WebhookService) dispatch(ctx context.Context, payload *models.WebhookPayload) {
+	var wg sync.WaitGroup
+	for _, target := range s.targets(ctx, payload) {
+		wg.Add(1)
+		go func(target webhookTarget) {
+			defer wg.Done()
 			// Отправляем вебхук с retry
-			s.sendWebhookWithRetry(ctx, payload)
+			s.sendWebhookWithRetry(ctx, target, payload)
+		}(target)
+	}
+	wg.Wait()
+}
+
+// targets отбирает получателей события. Адрес из WEBHOOK_URL получает только события арендатора default,
+// остальные арендаторы настраивают доставку подписками.
+func (s *WebhookService) targets(ctx context.Context, payload *models.WebhookPayload) []webhookTarget {
+	var targets []webhookTarget
+	if s.config.URL != "" && payloadTenant(payload) == models.DefaultTenant {
+		targets = append(targets, webhookTarget{
+			url:     s.config.URL,
+			secrets: []string{s.config.Secret, s.config.PreviousSecret},
+		})
+	}
+
+	subscriptions := s.activeSubscriptions(ctx)
+	for i := range subscriptions {
+		sub := &subscriptions[i]
+		if subscriptionMatches(sub, payload) {
+			targets = append(targets, webhookTarget{
+				subscriptionID: sub.ID,
+				url:            sub.URL,
+				secrets:        []string{sub.Secret},
+			})
 		}
 	}
+
+	return targets
+}
+
+// redeliver отправляет повторную доставку из dead-letter исходному получателю без проверки фильтров
+func (s *WebhookService) redeliver(ctx context.Context, subscriptionID uuid.UUID, payload *models.WebhookPayload) {
+	target, err := s.target(ctx, payloadTenant(payload), subscriptionID)
+	if err != nil {
+		log.Printf("webhook worker: replay of %s event: %v", payload.Event, err)
+		return
+	}
+	s.sendWebhookWithRetry(ctx, *target, payload)
+}
+
+// target возвращает получателя по ID подписки арендатора; uuid.Nil — адрес из WEBHOOK_URL
+func (s *WebhookService) target(ctx context.Context, tenantID string, subscriptionID uuid.UUID) (*webhookTarget, error) {
+	if subscriptionID == uuid.Nil {
+		if s.config.URL == "" || tenantID != models.DefaultTenant {
+			return nil, fmt.Errorf("%w: WEBHOOK_URL is not configured", ErrReplayTargetGone)
+		}
+		return &webhookTarget{url: s.config.URL, secrets: []string{s.config.Secret, s.config.PreviousSecret}}, nil
+	}
+
+	sub, err := s.subscriptionRepo.GetByID(ctx, tenantID, subscriptionID)
+	if err != nil {
+		if err.Error() == "subscription not found" {
+			return nil, fmt.Errorf("%w: subscription %s was deleted", ErrReplayTargetGone, subscriptionID)
+		}
+		return nil, err
+	}
+	return &webhookTarget{subscriptionID: sub.ID, url: sub.URL, secrets: []string{sub.Secret}}, nil
+}
+
+// activeSubscriptions возвращает подписки, перечитывая их из БД не чаще SubscriptionsRefresh.
+// При ошибке чтения используется предыдущий список.
+func (s *WebhookService) activeSubscriptions(ctx context.Context) []models.WebhookSubscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.loadedAt) < s.config.SubscriptionsRefresh {
+		return s.subscriptions
+	}
+
+	subscriptions, err := s.subscriptionRepo.ListActive(ctx)
+	if err != nil {
+		log.Printf("webhook worker: %v", err)
+		return s.subscriptions
+	}
+	s.subscriptions = subscriptions
+	s.loadedAt = time.Now()
+
+	return s.subscriptions
 }
 
-func (s *WebhookService) sendWebhookWithRetry(ctx context.Context, payload *models.WebhookPayload) {
+// sendWebhookWithRetry отправляет событие получателю; после исчерпания попыток
+// доставка сохраняется в dead-letter вместе с последней ошибкой
+func (s *WebhookService) sendWebhookWithRetry(ctx context.Context, target webhookTarget, payload *models.WebhookPayload) {
+	var (
+		statusCode int
+		err        error
+	)
 	for attempt := 1; attempt <= s.config.RetryAttempts; attempt++ {
-		err := s.sendWebhook(ctx, payload)
+		s.beat()
+		statusCode, err = s.sendWebhook(ctx, target, payload)
+		observeAttempt(statusCode, err)
 		if err == nil {
 			return // Успешно отправлено
 		}
@@ -61,34 +246,86 @@ func (s *WebhookService) sendWebhookWithRetry(ctx context.Context, payload *mode
 		if attempt < s.config.RetryAttempts {
 			// Экспоненциальная задержка
 			delay := time.Duration(attempt) * s.config.RetryDelay
-			time.Sleep(delay)
+			select {
+			case <-ctx.Done():
+			case <-time.After(delay):
+			}
+		}
+		if ctx.Err() != nil {
+			return // сервис останавливается, событие останется неподтвержденным
 		}
 	}
+	if err == nil {
+		return
+	}
+
+	metrics.WebhookRetriesExhausted.Inc()
+	deadLetter := models.DeadLetter{
+		ID:             uuid.New(),
+		TenantID:       payloadTenant(payload),
+		SubscriptionID: target.subscriptionID,
+		URL:            target.url,
+		Payload:        *payload,
+		LastError:      err.Error(),
+		StatusCode:     statusCode,
+		Attempts:       s.config.RetryAttempts,
+		FailedAt:       time.Now(),
+	}
+	if err := s.deadLetterRepo.Add(ctx, deadLetter); err != nil {
+		log.Printf("webhook worker: %s event to %s lost: %v", payload.Event, target.url, err)
+	}
 }
 
-func (s *WebhookService) sendWebhook(ctx context.Context, payload *models.WebhookPayload) error {
+// payloadTenant возвращает арендатора события; события без арендатора поставлены в очередь
+// до появления арендаторов и относятся к default
+func payloadTenant(payload *models.WebhookPayload) string {
+	if payload.TenantID == "" {
+		return models.DefaultTenant
+	}
+	return payload.TenantID
+}
+
+func observeAttempt(statusCode int, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+	code := "none"
+	if statusCode != 0 {
+		code = strconv.Itoa(statusCode)
+	}
+	metrics.WebhookDeliveryAttempts.WithLabelValues(outcome, code).Inc()
+}
+
+// sendWebhook возвращает код ответа получателя; 0 — ответ не получен
+func (s *WebhookService) sendWebhook(ctx context.Context, target webhookTarget, payload *models.WebhookPayload) (int, error) {
 	data, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+		return 0, fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", s.config.URL, bytes.NewBuffer(data))
+	req, err := http.NewRequestWithContext(ctx, "POST", target.url, bytes.NewBuffer(data))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	timestamp := time.Now().Unix()
+	if signature := signing.SignatureHeaderValue(target.secrets, timestamp, data); signature != "" {
+		req.Header.Set(signing.TimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(signing.SignatureHeader, signature)
+	}
 
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return 0, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, string(body))
+		return resp.StatusCode, fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, string(body))
 	}
 
-	return nil
+	return resp.StatusCode, nil
 }
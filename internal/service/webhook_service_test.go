@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"geo_system_core/internal/config"
+	"geo_system_core/internal/models"
+	"geo_system_core/internal/redistest"
+	"geo_system_core/internal/repository/redis"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSendWebhookWithRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int // сколько первых попыток получатель отвечает ошибкой
+		cancel     bool
+		attempts   int
+		deadLetter bool
+	}{
+		{name: "Доставлено с первой попытки", failures: 0, attempts: 1},
+		{name: "Доставлено после повтора", failures: 2, attempts: 3},
+		{name: "Попытки исчерпаны", failures: 10, attempts: 3, deadLetter: true},
+		{name: "Остановка сервиса", failures: 10, cancel: true, attempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if int(calls.Add(1)) <= tt.failures {
+					http.Error(w, "unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer receiver.Close()
+
+			deadLetters := redis.NewDeadLetterRepository(redistest.NewServer(t).NewClient(t))
+			s := &WebhookService{
+				deadLetterRepo: deadLetters,
+				config:         &config.WebhookConfig{RetryAttempts: 3, RetryDelay: time.Millisecond},
+				client:         &http.Client{Timeout: time.Second},
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				// Доставка прерывается во время первой задержки
+				s.config.RetryDelay = time.Hour
+				time.AfterFunc(50*time.Millisecond, cancel)
+			}
+
+			payload := &models.WebhookPayload{Event: "zone.enter", TenantID: "north", UserID: "user-1", Timestamp: time.Now()}
+			s.sendWebhookWithRetry(ctx, webhookTarget{url: receiver.URL}, payload)
+
+			if int(calls.Load()) != tt.attempts {
+				t.Errorf("sendWebhookWithRetry() made %d attempts, expected %d", calls.Load(), tt.attempts)
+			}
+
+			stored, total, err := deadLetters.List(context.Background(), "north", 0, 10)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if (total == 1) != tt.deadLetter || len(stored) != total {
+				t.Fatalf("dead letters = %d, expected dead letter: %v", total, tt.deadLetter)
+			}
+			if !tt.deadLetter {
+				return
+			}
+			dl := stored[0]
+			if dl.URL != receiver.URL || dl.Attempts != 3 || dl.StatusCode != http.StatusServiceUnavailable || dl.LastError == "" {
+				t.Errorf("dead letter = %+v, expected url, 3 attempts, status 503 and last error", dl)
+			}
+			if dl.Payload.Event != payload.Event || dl.Payload.UserID != payload.UserID {
+				t.Errorf("dead letter payload = %+v, expected %+v", dl.Payload, *payload)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Заголовки подписанной доставки вебхука
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+)
+
+const signatureScheme = "v1"
+
+var (
+	ErrMissingSignature  = errors.New("missing webhook signature")
+	ErrInvalidTimestamp  = errors.New("invalid webhook timestamp")
+	ErrTimestampTooOld   = errors.New("webhook timestamp is outside of the tolerance window")
+	ErrSignatureMismatch = errors.New("webhook signature mismatch")
+)
+
+// Sign вычисляет HMAC-SHA256 от строки "<timestamp>.<body>" в шестнадцатеричном виде
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignatureHeaderValue формирует значение заголовка подписи вида "v1=<hex>,v1=<hex>".
+// На время смены ключа передаются подписи всеми непустыми секретами, чтобы получатель
+// мог проверить доставку как старым, так и новым ключом.
+func SignatureHeaderValue(secrets []string, timestamp int64, body []byte) string {
+	var parts []string
+	for _, secret := range secrets {
+		if secret != "" {
+			parts = append(parts, signatureScheme+"="+Sign(secret, timestamp, body))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Verify проверяет, что метка времени не старше tolerance и хотя бы одна подпись
+// из заголовка совпадает с подписью одним из секретов получателя
+func Verify(secrets []string, signatureHeader, timestampHeader string, body []byte, tolerance time.Duration, now time.Time) error {
+	if signatureHeader == "" {
+		return ErrMissingSignature
+	}
+
+	timestamp, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	if diff := now.Sub(time.Unix(timestamp, 0)); diff > tolerance || diff < -tolerance {
+		return ErrTimestampTooOld
+	}
+
+	for _, part := range strings.Split(signatureHeader, ",") {
+		scheme, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || scheme != signatureScheme {
+			continue
+		}
+		received, err := hex.DecodeString(value)
+		if err != nil {
+			continue
+		}
+		for _, secret := range secrets {
+			if secret == "" {
+				continue
+			}
+			expected, _ := hex.DecodeString(Sign(secret, timestamp, body))
+			if hmac.Equal(received, expected) {
+				return nil
+			}
+		}
+	}
+
+	return ErrSignatureMismatch
+}
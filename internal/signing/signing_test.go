@@ -0,0 +1,44 @@
+package signing
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1717243200, 0)
+	body := []byte(`{"event":"zone.enter"}`)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	tests := []struct {
+		name          string
+		senderSecrets []string
+		verifySecrets []string
+		timestamp     string
+		body          []byte
+		expected      error
+	}{
+		{"Совпадающий секрет", []string{"new"}, []string{"new"}, timestamp, body, nil},
+		{"Смена ключа: получатель знает только старый", []string{"new", "old"}, []string{"old"}, timestamp, body, nil},
+		{"Смена ключа: получатель знает только новый", []string{"new", "old"}, []string{"new"}, timestamp, body, nil},
+		{"Чужой секрет", []string{"forged"}, []string{"new"}, timestamp, body, ErrSignatureMismatch},
+		{"Подмененное тело", []string{"new"}, []string{"new"}, timestamp, []byte(`{"event":"zone.exit"}`), ErrSignatureMismatch},
+		{"Устаревшая метка времени", []string{"new"}, []string{"new"}, strconv.FormatInt(now.Add(-time.Hour).Unix(), 10), body, ErrTimestampTooOld},
+		{"Некорректная метка времени", []string{"new"}, []string{"new"}, "yesterday", body, ErrInvalidTimestamp},
+		{"Нет подписи", nil, []string{"new"}, timestamp, body, ErrMissingSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, _ := strconv.ParseInt(tt.timestamp, 10, 64)
+			header := SignatureHeaderValue(tt.senderSecrets, ts, body)
+
+			err := Verify(tt.verifySecrets, header, tt.timestamp, tt.body, 5*time.Minute, now)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Verify() error = %v, expected %v", err, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS update_incidents_updated_at ON incidents;
+DROP FUNCTION IF EXISTS update_updated_at_column();
+DROP TABLE IF EXISTS location_checks;
+DROP TABLE IF EXISTS incidents;
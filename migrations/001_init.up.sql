@@ -40,6 +40,7 @@ END;
 $$ language 'plpgsql';
 
 -- Триггер для автоматического обновления updated_at
+DROP TRIGGER IF EXISTS update_incidents_updated_at ON incidents;
 CREATE TRIGGER update_incidents_updated_at BEFORE UPDATE ON incidents
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
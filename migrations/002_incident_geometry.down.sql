@@ -0,0 +1,2 @@
+ALTER TABLE incidents DROP CONSTRAINT IF EXISTS incidents_geometry_type_check;
+ALTER TABLE incidents DROP COLUMN IF EXISTS geometry;
@@ -0,0 +1,7 @@
+-- Полигональная геометрия зоны в формате GeoJSON (Polygon или MultiPolygon).
+-- Для таких инцидентов latitude/longitude/radius описывают окружность вокруг полигона.
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS geometry JSONB;
+
+ALTER TABLE incidents DROP CONSTRAINT IF EXISTS incidents_geometry_type_check;
+ALTER TABLE incidents ADD CONSTRAINT incidents_geometry_type_check
+    CHECK (geometry IS NULL OR geometry->>'type' IN ('Polygon', 'MultiPolygon'));
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_incidents_expires;
+ALTER TABLE incidents DROP CONSTRAINT IF EXISTS incidents_schedule_check;
+ALTER TABLE incidents DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE incidents DROP COLUMN IF EXISTS starts_at;
@@ -0,0 +1,11 @@
+-- Окно действия инцидента: до starts_at зона еще не действует, после expires_at
+-- планировщик переводит инцидент в статус resolved
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS starts_at TIMESTAMPTZ;
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+ALTER TABLE incidents DROP CONSTRAINT IF EXISTS incidents_schedule_check;
+ALTER TABLE incidents ADD CONSTRAINT incidents_schedule_check
+    CHECK (starts_at IS NULL OR expires_at IS NULL OR expires_at > starts_at);
+
+CREATE INDEX IF NOT EXISTS idx_incidents_expires ON incidents(expires_at)
+    WHERE is_active = true AND status = 'active' AND expires_at IS NOT NULL;
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_api_keys_tenant;
+DROP INDEX IF EXISTS idx_webhook_subscriptions_tenant;
+DROP INDEX IF EXISTS idx_location_checks_tenant;
+DROP INDEX IF EXISTS idx_incidents_tenant;
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE webhook_subscriptions DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE location_checks DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE incidents DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,19 @@
+-- Арендаторы (муниципалитеты). Все данные принадлежат одному арендатору;
+-- существующие данные переносятся в арендатора default
+CREATE TABLE IF NOT EXISTS tenants (
+    id VARCHAR(63) PRIMARY KEY CHECK (id ~ '^[a-z0-9][a-z0-9-]*$'),
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO tenants (id, name) VALUES ('default', 'Default') ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE location_checks ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE webhook_subscriptions ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+
+CREATE INDEX IF NOT EXISTS idx_incidents_tenant ON incidents(tenant_id, created_at) WHERE is_active = true;
+CREATE INDEX IF NOT EXISTS idx_location_checks_tenant ON location_checks(tenant_id, created_at) WHERE has_danger = true;
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_tenant ON webhook_subscriptions(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_api_keys_tenant ON api_keys(tenant_id);
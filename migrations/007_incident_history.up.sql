@@ -0,0 +1,28 @@
+-- История изменений инцидентов: снимки до и после каждого изменения и автор изменения
+CREATE TABLE IF NOT EXISTS incident_history (
+    id BIGSERIAL PRIMARY KEY,
+    incident_id UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    tenant_id VARCHAR(63) NOT NULL REFERENCES tenants(id),
+    version INTEGER NOT NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('created', 'updated', 'status_changed', 'deleted', 'snapshot')),
+    before JSONB,
+    after JSONB,
+    actor_key_id UUID,
+    actor VARCHAR(255) NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (incident_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_history_changed ON incident_history(incident_id, changed_at);
+
+-- Инциденты, созданные до появления истории, получают исходный снимок текущего состояния.
+-- created_at и updated_at хранятся без часового пояса и приводятся к формату RFC 3339 в UTC.
+INSERT INTO incident_history (incident_id, tenant_id, version, action, before, after, actor, changed_at)
+SELECT i.id, i.tenant_id, 1, 'snapshot', NULL,
+       to_jsonb(i) || jsonb_build_object(
+           'created_at', to_char(i.created_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+           'updated_at', to_char(i.updated_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"')
+       ),
+       'migration', i.updated_at AT TIME ZONE 'UTC'
+FROM incidents i
+ON CONFLICT (incident_id, version) DO NOTHING;
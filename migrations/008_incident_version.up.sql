@@ -0,0 +1,12 @@
+-- Версия инцидента для оптимистичной блокировки (ETag / If-Match).
+-- Совпадает с номером последней записи в incident_history.
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+UPDATE incidents i
+SET version = h.version
+FROM (
+    SELECT incident_id, MAX(version) AS version
+    FROM incident_history
+    GROUP BY incident_id
+) h
+WHERE h.incident_id = i.id;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_incidents_tenant_updated;
+DROP INDEX IF EXISTS idx_incidents_search;
@@ -0,0 +1,6 @@
+-- Полнотекстовый поиск по названию и описанию инцидента.
+-- Выражение должно совпадать с incidentSearchVector в репозитории.
+CREATE INDEX IF NOT EXISTS idx_incidents_search
+    ON incidents USING GIN (to_tsvector('simple', title || ' ' || COALESCE(description, '')));
+
+CREATE INDEX IF NOT EXISTS idx_incidents_tenant_updated ON incidents(tenant_id, updated_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_location_checks_tenant_keyset;
+DROP INDEX IF EXISTS idx_incidents_tenant_keyset;
@@ -0,0 +1,6 @@
+-- Индексы для постраничного просмотра по курсору (created_at, id)
+CREATE INDEX IF NOT EXISTS idx_incidents_tenant_keyset
+    ON incidents(tenant_id, created_at DESC, id DESC) WHERE is_active = true;
+
+CREATE INDEX IF NOT EXISTS idx_location_checks_tenant_keyset
+    ON location_checks(tenant_id, created_at DESC, id DESC);
@@ -0,0 +1,8 @@
+// Package migrations содержит SQL-миграции схемы, встроенные в бинарный файл.
+// Файлы называются <версия>_<имя>.up.sql и <версия>_<имя>.down.sql.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -2,14 +2,44 @@ package main
 
 import (
 	"encoding/json"
+	"geo_system_core/internal/signing"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"time"
 )
 
 func main() {
+	// Секреты для проверки подписи; если не заданы, подпись не проверяется
+	secrets := []string{os.Getenv("WEBHOOK_SECRET"), os.Getenv("WEBHOOK_SECRET_PREVIOUS")}
+	verify := secrets[0] != "" || secrets[1] != ""
+
+	tolerance := 5 * time.Minute
+	if value, err := time.ParseDuration(os.Getenv("WEBHOOK_TOLERANCE")); err == nil {
+		tolerance = value
+	}
+
 	http.HandleFunc("/webhook", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if verify {
+			err := signing.Verify(secrets,
+				r.Header.Get(signing.SignatureHeader), r.Header.Get(signing.TimestampHeader),
+				body, tolerance, time.Now())
+			if err != nil {
+				log.Printf("Webhook rejected: %v", err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+
 		var payload interface{}
-		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		if err := json.Unmarshal(body, &payload); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
@@ -21,6 +51,9 @@ func main() {
 		_, _ = w.Write([]byte(`{"status":"ok"}`))
 	})
 
+	if verify {
+		log.Println("Webhook signature verification enabled")
+	}
 	log.Println("Webhook stub listening on :9090")
 	if err := http.ListenAndServe(":9090", nil); err != nil {
 		log.Fatalf("server failed: %v", err)